@@ -0,0 +1,523 @@
+package dotprompt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ArchiveFormat represents the format of a prompt bundle archive.
+type ArchiveFormat int
+
+const (
+	// ZipArchive is a zip archive.
+	ZipArchive ArchiveFormat = iota
+
+	// TarGzArchive is a gzip compressed tar archive.
+	TarGzArchive
+)
+
+const (
+	defaultMaxArchiveSize    = 100 << 20
+	defaultMaxArchiveEntries = 10000
+)
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// ArchiveStoreError represents an error encountered when reading or writing a prompt bundle archive.
+// It contains a message describing the error and an optional underlying error.
+type ArchiveStoreError struct {
+	Message string
+	Err     error
+}
+
+// Error returns the error message contained in the ArchiveStoreError.
+func (e ArchiveStoreError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e ArchiveStoreError) Unwrap() error {
+	return e.Err
+}
+
+// BundleManifest represents the manifest written to the root of a prompt bundle, listing each file in the bundle
+// with its SHA-256 checksum.
+type BundleManifest struct {
+	Files []BundleEntry `json:"files"`
+}
+
+// BundleEntry represents a single file listed in a BundleManifest.
+type BundleEntry struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// ArchiveStoreOption configures optional behaviour of an ArchiveStore.
+type ArchiveStoreOption func(s *ArchiveStore)
+
+// WithRequiredManifest configures the ArchiveStore to reject archives which do not contain a bundle manifest. By
+// default, archives without a manifest are loaded without verification.
+func WithRequiredManifest() ArchiveStoreOption {
+	return func(s *ArchiveStore) {
+		s.requireManifest = true
+	}
+}
+
+// WithArchiveLoaderOptions sets the options used to load the prompt files from the archive, such as WithNamespaces.
+func WithArchiveLoaderOptions(options ...LoaderOption) ArchiveStoreOption {
+	return func(s *ArchiveStore) {
+		s.loaderOptions = append(s.loaderOptions, options...)
+	}
+}
+
+// WithMaxArchiveSize sets the maximum total size, in bytes, of the uncompressed files in the archive, which defaults
+// to 100 MiB. Larger archives are rejected when the store is created.
+func WithMaxArchiveSize(size int64) ArchiveStoreOption {
+	return func(s *ArchiveStore) {
+		s.maxSize = size
+	}
+}
+
+// WithMaxArchiveEntries sets the maximum number of entries in the archive, which defaults to 10,000. Archives with more
+// entries are rejected when the store is created.
+func WithMaxArchiveEntries(count int) ArchiveStoreOption {
+	return func(s *ArchiveStore) {
+		s.maxEntries = count
+	}
+}
+
+// ArchiveStore represents a storage system which reads prompt files from a zip or tar.gz archive. Prompt files are
+// loaded in the same way as an FSStore. If the archive contains a manifest.json at its root, as written by
+// WriteBundle, then every prompt file is verified against the checksums in the manifest when it is loaded.
+type ArchiveStore struct {
+	archive         *zip.Reader
+	requireManifest bool
+	maxSize         int64
+	maxEntries      int
+	loaderOptions   []LoaderOption
+	store           *FSStore
+}
+
+// NewArchiveStore creates a new ArchiveStore which reads prompt files from the zip or tar.gz archive at the specified
+// path.
+func NewArchiveStore(archivePath string, options ...ArchiveStoreOption) (*ArchiveStore, error) {
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return nil, &ArchiveStoreError{
+			Message: fmt.Sprintf("failed to read archive %s: %v", archivePath, err),
+			Err:     err,
+		}
+	}
+
+	return NewArchiveStoreFromBytes(data, options...)
+}
+
+// NewArchiveStoreFromBytes creates a new ArchiveStore which reads prompt files from a zip or tar.gz archive held in
+// memory.
+func NewArchiveStoreFromBytes(data []byte, options ...ArchiveStoreOption) (*ArchiveStore, error) {
+	return NewArchiveStoreFromReaderAt(bytes.NewReader(data), int64(len(data)), options...)
+}
+
+// NewArchiveStoreFromReaderAt creates a new ArchiveStore which reads prompt files from a zip or tar.gz archive of the
+// given size. The format of the archive is detected from its content.
+func NewArchiveStoreFromReaderAt(r io.ReaderAt, size int64, options ...ArchiveStoreOption) (*ArchiveStore, error) {
+	store := &ArchiveStore{
+		maxSize:    defaultMaxArchiveSize,
+		maxEntries: defaultMaxArchiveEntries,
+	}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	if store.maxSize < 1 || store.maxEntries < 1 {
+		return nil, &ArchiveStoreError{
+			Message: fmt.Sprintf("invalid archive limits: %d bytes, %d entries", store.maxSize, store.maxEntries),
+		}
+	}
+
+	header := make([]byte, len(zipMagic))
+	if _, err := r.ReadAt(header, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, &ArchiveStoreError{
+			Message: fmt.Sprintf("failed to read archive: %v", err),
+			Err:     err,
+		}
+	}
+
+	var archive *zip.Reader
+	var err error
+
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		if archive, err = zip.NewReader(r, size); err == nil {
+			err = checkZipLimits(archive, store.maxSize, store.maxEntries)
+		}
+	case bytes.HasPrefix(header, gzipMagic):
+		archive, err = repackTarGz(io.NewSectionReader(r, 0, size), store.maxSize, store.maxEntries)
+	default:
+		return nil, &ArchiveStoreError{
+			Message: "The archive is not a zip or tar.gz archive",
+		}
+	}
+
+	if err != nil {
+		return nil, &ArchiveStoreError{
+			Message: fmt.Sprintf("failed to read archive: %v", err),
+			Err:     err,
+		}
+	}
+
+	store.archive = archive
+	store.store = NewFSStore(readDirFS{archive}, store.loaderOptions...)
+
+	return store, nil
+}
+
+// Errors returns the errors for the files skipped by the most recent call to Load, when the store was created with
+// WithLenient in its loader options.
+func (s *ArchiveStore) Errors() []LoadError {
+	return s.store.Errors()
+}
+
+// Load verifies the archive against its manifest, if it has one, and retrieves all prompt files from the archive.
+// Returns a slice of PromptFile and an error if verification or loading fails.
+func (s *ArchiveStore) Load() ([]PromptFile, error) {
+	return s.LoadContext(context.Background())
+}
+
+// LoadContext verifies and retrieves all prompt files from the archive in the same way as Load, stopping early if the
+// context is done.
+func (s *ArchiveStore) LoadContext(ctx context.Context) ([]PromptFile, error) {
+	if err := s.verify(); err != nil {
+		return nil, err
+	}
+
+	promptFiles, err := s.store.LoadContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range promptFiles {
+		promptFiles[i].Source.Loader = ArchiveSource
+	}
+
+	return promptFiles, nil
+}
+
+// verify checks that every prompt file in the archive is listed in the manifest with a matching checksum, and that
+// every file listed in the manifest is present.
+func (s *ArchiveStore) verify() error {
+	manifestContent, err := fs.ReadFile(s.archive, manifestFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		if s.requireManifest {
+			return &ArchiveStoreError{
+				Message: "the archive does not contain a manifest",
+			}
+		}
+		return nil
+	} else if err != nil {
+		return &ArchiveStoreError{
+			Message: fmt.Sprintf("failed to read manifest: %v", err),
+			Err:     err,
+		}
+	}
+
+	var manifest BundleManifest
+	if err = json.Unmarshal(manifestContent, &manifest); err != nil {
+		return &ArchiveStoreError{
+			Message: fmt.Sprintf("failed to parse manifest: %v", err),
+			Err:     err,
+		}
+	}
+
+	checksums := make(map[string]string, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		checksums[entry.Path] = entry.SHA256
+	}
+
+	err = fs.WalkDir(s.archive, ".", func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		if entry.IsDir() || filePath == manifestFileName {
+			return nil
+		}
+
+		expected, listed := checksums[filePath]
+		if !listed {
+			if isPromptFilePath(filePath) {
+				return &ArchiveStoreError{
+					Message: fmt.Sprintf("the archive contains a file which is not in the manifest: %s", filePath),
+				}
+			}
+			return nil
+		}
+		delete(checksums, filePath)
+
+		content, readErr := fs.ReadFile(s.archive, filePath)
+		if readErr != nil {
+			return &ArchiveStoreError{
+				Message: fmt.Sprintf("failed to read %s: %v", filePath, readErr),
+				Err:     readErr,
+			}
+		}
+
+		if checksum(content) != expected {
+			return &ArchiveStoreError{
+				Message: fmt.Sprintf("checksum mismatch for %s", filePath),
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(checksums) > 0 {
+		missing := make([]string, 0, len(checksums))
+		for filePath := range checksums {
+			missing = append(missing, filePath)
+		}
+		slices.Sort(missing)
+
+		return &ArchiveStoreError{
+			Message: fmt.Sprintf("the archive is missing files listed in the manifest: %s", strings.Join(missing, ", ")),
+		}
+	}
+
+	return nil
+}
+
+// WriteBundle writes the prompt files held by the manager to w as an archive of the specified format. Each prompt file
+// is written as <name>.prompt, or with the .prompt.json or .prompt.toml extension for prompt files in the JSON or TOML
+// format, alongside a manifest.json listing the SHA-256 checksum of every file.
+func WriteBundle(w io.Writer, manager *Manager, format ArchiveFormat) error {
+	if manager == nil {
+		return &ArchiveStoreError{
+			Message: "manager cannot be nil",
+		}
+	}
+
+	names := manager.ListPromptFileNames()
+	slices.Sort(names)
+
+	paths := make([]string, 0, len(names)+1)
+	files := make(map[string][]byte, len(names)+1)
+	manifest := BundleManifest{Files: make([]BundleEntry, 0, len(names))}
+
+	for _, name := range names {
+		// Namespaced prompt files are written to the directory of their namespace, and serialize without the namespace in
+		// their name, so that they are named the same when loaded using WithNamespaces
+		promptFile := manager.PromptFiles[name]
+		content, err := promptFile.Serialize()
+		if err != nil {
+			return err
+		}
+
+		filePath := name + promptFile.Format.extension()
+		paths = append(paths, filePath)
+		files[filePath] = content
+		manifest.Files = append(manifest.Files, BundleEntry{Path: filePath, SHA256: checksum(content)})
+	}
+
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return &ArchiveStoreError{
+			Message: fmt.Sprintf("failed to write manifest: %v", err),
+			Err:     err,
+		}
+	}
+	files[manifestFileName] = manifestContent
+
+	// The manifest is written first so that it can be read without scanning a streamed archive
+	paths = append([]string{manifestFileName}, paths...)
+
+	switch format {
+	case ZipArchive:
+		err = writeZip(w, paths, files)
+	case TarGzArchive:
+		err = writeTarGz(w, paths, files)
+	default:
+		return &ArchiveStoreError{
+			Message: fmt.Sprintf("unsupported archive format: %d", format),
+		}
+	}
+
+	if err != nil {
+		return &ArchiveStoreError{
+			Message: fmt.Sprintf("failed to write bundle: %v", err),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// writeZip writes the files to w as a zip archive, in the order given by paths.
+func writeZip(w io.Writer, paths []string, files map[string][]byte) error {
+	writer := zip.NewWriter(w)
+
+	for _, filePath := range paths {
+		fileWriter, err := writer.Create(filePath)
+		if err != nil {
+			return err
+		}
+
+		if _, err = fileWriter.Write(files[filePath]); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// writeTarGz writes the files to w as a gzip compressed tar archive, in the order given by paths.
+func writeTarGz(w io.Writer, paths []string, files map[string][]byte) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, filePath := range paths {
+		content := files[filePath]
+		err := tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     filePath,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			ModTime:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err = tarWriter.Write(content); err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+
+	return gzipWriter.Close()
+}
+
+// checkZipLimits returns an error if the zip archive has more than maxEntries entries, or the declared uncompressed
+// sizes of its files total more than maxSize bytes. archive/zip fails to read any file which is larger than its
+// declared size, so the limits hold when the files are read.
+func checkZipLimits(archive *zip.Reader, maxSize int64, maxEntries int) error {
+	if len(archive.File) > maxEntries {
+		return fmt.Errorf("the archive contains more than %d entries", maxEntries)
+	}
+
+	var total uint64
+	for _, file := range archive.File {
+		total += file.UncompressedSize64
+		if total > uint64(maxSize) {
+			return fmt.Errorf("the archive exceeds the maximum uncompressed size of %d bytes", maxSize)
+		}
+	}
+
+	return nil
+}
+
+// repackTarGz reads the regular files from a tar.gz archive and repacks them into an in-memory zip archive, so that
+// both archive formats can be read through the fs.FS implementation of archive/zip. Reading stops with an error once
+// the archive has more than maxEntries entries or more than maxSize bytes of uncompressed files.
+func repackTarGz(r io.Reader, maxSize int64, maxEntries int) (*zip.Reader, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	tarReader := tar.NewReader(gzipReader)
+	remaining := maxSize
+
+	for entries := 0; ; entries++ {
+		header, nextErr := tarReader.Next()
+		if errors.Is(nextErr, io.EOF) {
+			break
+		} else if nextErr != nil {
+			return nil, nextErr
+		}
+
+		if entries >= maxEntries {
+			return nil, fmt.Errorf("the archive contains more than %d entries", maxEntries)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		fileWriter, createErr := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: header.ModTime,
+		})
+		if createErr != nil {
+			return nil, createErr
+		}
+
+		if header.Size > remaining {
+			return nil, fmt.Errorf("the archive exceeds the maximum uncompressed size of %d bytes", maxSize)
+		}
+
+		written, copyErr := io.CopyN(fileWriter, tarReader, remaining+1)
+		if copyErr != nil && !errors.Is(copyErr, io.EOF) {
+			return nil, copyErr
+		}
+
+		if remaining -= written; remaining < 0 {
+			return nil, fmt.Errorf("the archive exceeds the maximum uncompressed size of %d bytes", maxSize)
+		}
+	}
+
+	if err = zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+}
+
+// checksum returns the hex encoded SHA-256 checksum of the content.
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// readDirFS adds the fs.ReadDirFS interface to a file system which does not implement it directly.
+type readDirFS struct {
+	fs.FS
+}
+
+// ReadDir reads the named directory and returns its entries sorted by filename.
+func (r readDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(r.FS, name)
+}
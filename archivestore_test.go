@@ -0,0 +1,375 @@
+package dotprompt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestBundle(t *testing.T, format ArchiveFormat) []byte {
+	t.Helper()
+
+	fileStore, err := NewFileStoreFromPath("file-store-tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewManagerFromLoader(fileStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err = WriteBundle(&buffer, manager, format); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// newTestZip creates a zip archive containing the files.
+func newTestZip(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range files {
+		fileWriter, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = fileWriter.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// readTestZip reads all files from a zip archive.
+func readTestZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string][]byte)
+	for _, file := range reader.File {
+		rc, openErr := file.Open()
+		if openErr != nil {
+			t.Fatal(openErr)
+		}
+
+		var content bytes.Buffer
+		if _, openErr = content.ReadFrom(rc); openErr != nil {
+			t.Fatal(openErr)
+		}
+		_ = rc.Close()
+
+		files[file.Name] = content.Bytes()
+	}
+
+	return files
+}
+
+func TestArchiveStore_Load_WithBundle(t *testing.T) {
+	tests := []struct {
+		name   string
+		format ArchiveFormat
+	}{
+		{"zip", ZipArchive},
+		{"tar-gz", TarGzArchive},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store, err := NewArchiveStoreFromBytes(newTestBundle(t, test.format), WithRequiredManifest())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			promptFiles, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := []string{"another-example-with-name", "basic"}
+			if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+				t.Errorf("Expected prompt files %v, got %v", expected, names)
+			}
+		})
+	}
+}
+
+func TestArchiveStore_Load_WithNamespaces(t *testing.T) {
+	loader := NewFSStore(fstest.MapFS{
+		"support/summarise.prompt": {Data: []byte(minimalPrompt)},
+		"sales/summarise.prompt":   {Data: []byte(minimalPrompt)},
+	}, WithNamespaces())
+
+	manager, err := NewManagerFromLoader(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err = WriteBundle(&buffer, manager, TarGzArchive); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewArchiveStoreFromBytes(buffer.Bytes(), WithArchiveLoaderOptions(WithNamespaces()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"sales/summarise", "support/summarise"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+}
+
+func TestArchiveStore_Load_FromPath(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "prompts.zip")
+	if err := os.WriteFile(archivePath, newTestBundle(t, ZipArchive), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewArchiveStore(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 2 {
+		t.Errorf("Expected 2 prompt files, got %d", len(promptFiles))
+	}
+}
+
+func TestArchiveStore_Load_WithTarGzWithoutManifest(t *testing.T) {
+	content, err := os.ReadFile("file-store-tests/sub-dir/another-with-name.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	headers := []*tar.Header{
+		{Typeflag: tar.TypeDir, Name: "./", Mode: 0755},
+		{Typeflag: tar.TypeDir, Name: "./prompts/", Mode: 0755},
+		{Typeflag: tar.TypeReg, Name: "./prompts/another.prompt", Mode: 0644, Size: int64(len(content))},
+		{Typeflag: tar.TypeSymlink, Name: "./prompts/link.prompt", Linkname: "another.prompt"},
+	}
+
+	for _, header := range headers {
+		if err = tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err = tarWriter.Write(content); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewArchiveStoreFromReaderAt(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := promptFileNames(promptFiles); !slices.Equal(names, []string{"another-example-with-name"}) {
+		t.Errorf("Expected prompt files [another-example-with-name], got %v", names)
+	}
+}
+
+func TestArchiveStore_Load_WithInvalidBundle_ReturnsError(t *testing.T) {
+	bundle := readTestZip(t, newTestBundle(t, ZipArchive))
+	basic := bundle["basic.prompt"]
+
+	tests := []struct {
+		name          string
+		modify        func(files map[string][]byte)
+		options       []ArchiveStoreOption
+		expectedError string
+	}{
+		{
+			"modified-file",
+			func(files map[string][]byte) {
+				files["basic.prompt"] = append(slices.Clone(basic), []byte("\n# changed\n")...)
+			},
+			nil,
+			"checksum mismatch for basic.prompt",
+		},
+		{
+			"unlisted-file",
+			func(files map[string][]byte) { files["extra/basic-copy.prompt"] = basic },
+			nil,
+			"the archive contains a file which is not in the manifest: extra/basic-copy.prompt",
+		},
+		{
+			"missing-file",
+			func(files map[string][]byte) { delete(files, "basic.prompt") },
+			nil,
+			"the archive is missing files listed in the manifest: basic.prompt",
+		},
+		{
+			"missing-manifest",
+			func(files map[string][]byte) { delete(files, manifestFileName) },
+			[]ArchiveStoreOption{WithRequiredManifest()},
+			"the archive does not contain a manifest",
+		},
+		{
+			"invalid-manifest",
+			func(files map[string][]byte) { files[manifestFileName] = []byte("{") },
+			nil,
+			"failed to parse manifest: unexpected end of JSON input",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			files := readTestZip(t, newTestBundle(t, ZipArchive))
+			test.modify(files)
+
+			store, err := NewArchiveStoreFromBytes(newTestZip(t, files), test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = store.Load()
+
+			var storeError *ArchiveStoreError
+			if !errors.As(err, &storeError) {
+				t.Fatalf("Expected ArchiveStoreError, got %T", err)
+			}
+
+			if storeError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, storeError.Error())
+			}
+		})
+	}
+}
+
+func TestNewArchiveStore_WithInvalidArchive_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		expectedError string
+	}{
+		{"empty", []byte{}, "The archive is not a zip or tar.gz archive"},
+		{"plain-text", []byte("name: not-an-archive"), "The archive is not a zip or tar.gz archive"},
+		{"truncated-gzip", []byte{0x1f, 0x8b, 0x08}, "failed to read archive: unexpected EOF"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewArchiveStoreFromBytes(test.data)
+
+			var storeError *ArchiveStoreError
+			if !errors.As(err, &storeError) {
+				t.Fatalf("Expected ArchiveStoreError, got %T", err)
+			}
+
+			if storeError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, storeError.Error())
+			}
+		})
+	}
+}
+
+func TestNewArchiveStore_WithLimits_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        ArchiveFormat
+		options       []ArchiveStoreOption
+		expectedError string
+	}{
+		{"zip-size", ZipArchive, []ArchiveStoreOption{WithMaxArchiveSize(16)}, "failed to read archive: the archive exceeds the maximum uncompressed size of 16 bytes"},
+		{"zip-entries", ZipArchive, []ArchiveStoreOption{WithMaxArchiveEntries(1)}, "failed to read archive: the archive contains more than 1 entries"},
+		{"tar-size", TarGzArchive, []ArchiveStoreOption{WithMaxArchiveSize(16)}, "failed to read archive: the archive exceeds the maximum uncompressed size of 16 bytes"},
+		{"tar-entries", TarGzArchive, []ArchiveStoreOption{WithMaxArchiveEntries(1)}, "failed to read archive: the archive contains more than 1 entries"},
+		{"invalid-limits", ZipArchive, []ArchiveStoreOption{WithMaxArchiveSize(0)}, "invalid archive limits: 0 bytes, 10000 entries"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewArchiveStoreFromBytes(newTestBundle(t, test.format), test.options...)
+
+			var storeError *ArchiveStoreError
+			if !errors.As(err, &storeError) {
+				t.Fatalf("Expected ArchiveStoreError, got %T", err)
+			}
+
+			if storeError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, storeError.Error())
+			}
+		})
+	}
+}
+
+func TestWriteBundle_WritesManifest(t *testing.T) {
+	files := readTestZip(t, newTestBundle(t, ZipArchive))
+
+	expected := []string{"another-example-with-name.prompt", "basic.prompt", manifestFileName}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	if !slices.Equal(names, expected) {
+		t.Errorf("Expected files %v, got %v", expected, names)
+	}
+
+	for _, name := range expected[:2] {
+		if !bytes.Contains(files[manifestFileName], []byte(checksum(files[name]))) {
+			t.Errorf("Expected manifest to contain the checksum of %s", name)
+		}
+	}
+}
+
+func TestWriteBundle_WithNilManager_ReturnsError(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteBundle(&buffer, nil, ZipArchive); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+}
@@ -0,0 +1,267 @@
+package dotprompt
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Builder constructs a PromptFile in code using a fluent API. Build applies the same validation and naming rules as
+// loading a prompt file from disk, so the name is cleaned and invalid parameter types, messages, and tools are
+// rejected.
+type Builder struct {
+	name       string
+	promptFile PromptFile
+	lastParam  string
+	err        error
+}
+
+// NewBuilder creates a new Builder for a prompt file with the specified name.
+func NewBuilder(name string) *Builder {
+	return &Builder{name: name}
+}
+
+// Model sets the model the prompt file is intended for.
+func (b *Builder) Model(model string) *Builder {
+	b.promptFile.Model = model
+	return b
+}
+
+// Temperature sets the sampling temperature.
+func (b *Builder) Temperature(temperature float32) *Builder {
+	b.promptFile.Config.Temperature = &temperature
+	return b
+}
+
+// MaxTokens sets the maximum number of tokens to generate.
+func (b *Builder) MaxTokens(maxTokens int) *Builder {
+	b.promptFile.Config.MaxTokens = &maxTokens
+	return b
+}
+
+// TopP sets the nucleus sampling probability.
+func (b *Builder) TopP(topP float32) *Builder {
+	b.promptFile.Config.TopP = &topP
+	return b
+}
+
+// TopK sets the number of most likely tokens to sample from.
+func (b *Builder) TopK(topK int) *Builder {
+	b.promptFile.Config.TopK = &topK
+	return b
+}
+
+// Stop sets the sequences which stop generation.
+func (b *Builder) Stop(sequences ...string) *Builder {
+	b.promptFile.Config.Stop = sequences
+	return b
+}
+
+// Seed sets the seed used for sampling.
+func (b *Builder) Seed(seed int) *Builder {
+	b.promptFile.Config.Seed = &seed
+	return b
+}
+
+// PresencePenalty sets the penalty applied to tokens which have already appeared.
+func (b *Builder) PresencePenalty(penalty float32) *Builder {
+	b.promptFile.Config.PresencePenalty = &penalty
+	return b
+}
+
+// FrequencyPenalty sets the penalty applied to tokens based on how often they have appeared.
+func (b *Builder) FrequencyPenalty(penalty float32) *Builder {
+	b.promptFile.Config.FrequencyPenalty = &penalty
+	return b
+}
+
+// ReasoningEffort sets the reasoning effort of reasoning models, such as "low" or "high".
+func (b *Builder) ReasoningEffort(effort string) *Builder {
+	b.promptFile.Config.ReasoningEffort = effort
+	return b
+}
+
+// Extra sets a model parameter which does not have a method of its own, using the name expected by the model.
+func (b *Builder) Extra(name string, value interface{}) *Builder {
+	if b.promptFile.Config.Extra == nil {
+		b.promptFile.Config.Extra = make(map[string]interface{})
+	}
+	b.promptFile.Config.Extra[name] = value
+	return b
+}
+
+// OutputFormat sets the format of the response.
+func (b *Builder) OutputFormat(format OutputFormat) *Builder {
+	b.promptFile.Config.OutputFormat = format
+	return b
+}
+
+// Cache sets whether responses to the prompt file can be cached.
+func (b *Builder) Cache(enabled bool) *Builder {
+	b.promptFile.Config.Cache = &enabled
+	return b
+}
+
+// MaxMediaSize sets the maximum size in bytes of any image or file parameter.
+func (b *Builder) MaxMediaSize(size int) *Builder {
+	b.promptFile.Config.Input.MaxMediaSize = size
+	return b
+}
+
+// DateLayouts sets the layouts, in addition to RFC 3339, accepted for datetime parameters given as strings.
+func (b *Builder) DateLayouts(layouts ...string) *Builder {
+	b.promptFile.Config.Input.DateLayouts = layouts
+	return b
+}
+
+// TimeZone sets the time zone datetime parameters are rendered in, such as "Europe/London".
+func (b *Builder) TimeZone(zone string) *Builder {
+	b.promptFile.Config.Input.TimeZone = zone
+	return b
+}
+
+// Coerce sets whether string, number, and bool parameters with a compatible type, such as the string "42" for a
+// number, are converted to the declared type.
+func (b *Builder) Coerce(enabled bool) *Builder {
+	b.promptFile.Config.Input.Coerce = enabled
+	return b
+}
+
+// Param adds an input parameter with the specified data type. Optional parameters are named with a `?` suffix, in the
+// same way as in a prompt file.
+func (b *Builder) Param(name string, dataType string) *Builder {
+	if b.promptFile.Config.Input.Parameters == nil {
+		b.promptFile.Config.Input.Parameters = make(map[string]string)
+	}
+	b.promptFile.Config.Input.Parameters[name] = dataType
+	b.lastParam = strings.TrimSuffix(name, "?")
+	return b
+}
+
+// Default sets the default value of the parameter most recently added with Param. Build returns an error if Default is
+// called before any parameters have been added.
+func (b *Builder) Default(value interface{}) *Builder {
+	if b.lastParam == "" {
+		if b.err == nil {
+			b.err = &PromptError{
+				Message: "a default value was provided before any parameters were added",
+			}
+		}
+		return b
+	}
+
+	if b.promptFile.Config.Input.Default == nil {
+		b.promptFile.Config.Input.Default = make(map[string]interface{})
+	}
+	b.promptFile.Config.Input.Default[b.lastParam] = value
+	return b
+}
+
+// ParamTimeZone sets the time zone of the datetime parameter most recently added with Param, in place of the time
+// zone set with TimeZone. Build returns an error if ParamTimeZone is called before any parameters have been added.
+func (b *Builder) ParamTimeZone(zone string) *Builder {
+	if b.lastParam == "" {
+		if b.err == nil {
+			b.err = &PromptError{
+				Message: "a time zone was provided before any parameters were added",
+			}
+		}
+		return b
+	}
+
+	if b.promptFile.Config.Input.TimeZones == nil {
+		b.promptFile.Config.Input.TimeZones = make(map[string]string)
+	}
+	b.promptFile.Config.Input.TimeZones[b.lastParam] = zone
+	return b
+}
+
+// System sets the system prompt template.
+func (b *Builder) System(template string) *Builder {
+	b.promptFile.Prompts.System = template
+	return b
+}
+
+// User sets the user prompt template.
+func (b *Builder) User(template string) *Builder {
+	b.promptFile.Prompts.User = template
+	return b
+}
+
+// Message adds a message template with the specified role, for prompt files using the messages form rather than a
+// system and user prompt.
+func (b *Builder) Message(role string, template string) *Builder {
+	b.promptFile.Messages = append(b.promptFile.Messages, MessageTemplate{Role: role, Content: template})
+	return b
+}
+
+// History adds the placeholder where conversation history is inserted, for prompt files using the messages form.
+func (b *Builder) History() *Builder {
+	b.promptFile.Messages = append(b.promptFile.Messages, MessageTemplate{Role: HistoryRole})
+	return b
+}
+
+// FewShot adds an example user prompt and response.
+func (b *Builder) FewShot(user string, response string) *Builder {
+	b.promptFile.FewShots = append(b.promptFile.FewShots, FewShotPromptPair{User: user, Response: response})
+	return b
+}
+
+// Tool adds a tool which is available to the model.
+func (b *Builder) Tool(tool Tool) *Builder {
+	b.promptFile.Tools = append(b.promptFile.Tools, tool)
+	return b
+}
+
+// Override sets the model and configuration values used in place of those of the prompt file when the profile is
+// active.
+func (b *Builder) Override(profile string, override ProfileOverride) *Builder {
+	if b.promptFile.Overrides == nil {
+		b.promptFile.Overrides = make(map[string]ProfileOverride)
+	}
+	b.promptFile.Overrides[profile] = override
+	return b
+}
+
+// Build validates and returns the prompt file. Returns an error if the prompt file is invalid, in the same way as
+// NewPromptFile. The builder can continue to be used after Build without affecting the prompt files it has returned.
+func (b *Builder) Build() (*PromptFile, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	promptFile := b.promptFile
+	promptFile.Config.Temperature = clonePointer(promptFile.Config.Temperature)
+	promptFile.Config.MaxTokens = clonePointer(promptFile.Config.MaxTokens)
+	promptFile.Config.Cache = clonePointer(promptFile.Config.Cache)
+	promptFile.Config.TopP = clonePointer(promptFile.Config.TopP)
+	promptFile.Config.TopK = clonePointer(promptFile.Config.TopK)
+	promptFile.Config.Stop = slices.Clone(promptFile.Config.Stop)
+	promptFile.Config.Seed = clonePointer(promptFile.Config.Seed)
+	promptFile.Config.PresencePenalty = clonePointer(promptFile.Config.PresencePenalty)
+	promptFile.Config.FrequencyPenalty = clonePointer(promptFile.Config.FrequencyPenalty)
+	promptFile.Config.Extra = maps.Clone(promptFile.Config.Extra)
+	promptFile.Config.Input.Parameters = maps.Clone(promptFile.Config.Input.Parameters)
+	promptFile.Config.Input.Default = maps.Clone(promptFile.Config.Input.Default)
+	promptFile.Config.Input.DateLayouts = slices.Clone(promptFile.Config.Input.DateLayouts)
+	promptFile.Config.Input.TimeZones = maps.Clone(promptFile.Config.Input.TimeZones)
+	promptFile.Messages = slices.Clone(promptFile.Messages)
+	promptFile.FewShots = slices.Clone(promptFile.FewShots)
+	promptFile.Tools = slices.Clone(promptFile.Tools)
+	promptFile.Overrides = maps.Clone(promptFile.Overrides)
+
+	if err := validatePromptFile(&promptFile, b.name); err != nil {
+		return nil, err
+	}
+
+	return &promptFile, nil
+}
+
+// clonePointer returns a pointer to a copy of the value, or nil if the pointer is nil.
+func clonePointer[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+	clone := *value
+	return &clone
+}
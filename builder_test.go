@@ -0,0 +1,252 @@
+package dotprompt
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	promptFile, err := NewBuilder("Research Assistant").
+		Model("gpt-4o").
+		Temperature(0.7).
+		MaxTokens(500).
+		OutputFormat(Json).
+		Param("topic", "string").
+		Default("Bluetooth").
+		Param("style?", "string").
+		System("You are a helpful research assistant").
+		User("Explain {{ topic }}").
+		FewShot("What is Wi-Fi?", "A wireless networking technology.").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if promptFile.Name != "research-assistant" {
+		t.Errorf("Expected name to be 'research-assistant', got '%s'", promptFile.Name)
+	}
+
+	if promptFile.Model != "gpt-4o" {
+		t.Errorf("Expected model to be 'gpt-4o', got '%s'", promptFile.Model)
+	}
+
+	if promptFile.Config.Temperature == nil || *promptFile.Config.Temperature != 0.7 {
+		t.Errorf("Expected temperature to be 0.7, got %v", promptFile.Config.Temperature)
+	}
+
+	if promptFile.Config.MaxTokens == nil || *promptFile.Config.MaxTokens != 500 {
+		t.Errorf("Expected max tokens to be 500, got %v", promptFile.Config.MaxTokens)
+	}
+
+	expectedParameters := map[string]string{"topic": "string", "style?": "string"}
+	if !maps.Equal(promptFile.Config.Input.Parameters, expectedParameters) {
+		t.Errorf("Expected parameters %v, got %v", expectedParameters, promptFile.Config.Input.Parameters)
+	}
+
+	expectedFewShots := []FewShotPromptPair{{User: "What is Wi-Fi?", Response: "A wireless networking technology."}}
+	if !reflect.DeepEqual(promptFile.FewShots, expectedFewShots) {
+		t.Errorf("Expected few shots %v, got %v", expectedFewShots, promptFile.FewShots)
+	}
+
+	userPrompt, err := promptFile.GetUserPrompt(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if userPrompt != "Explain Bluetooth" {
+		t.Errorf("Expected user prompt to be 'Explain Bluetooth', got '%s'", userPrompt)
+	}
+}
+
+func TestBuilder_Build_WithMessages(t *testing.T) {
+	promptFile, err := NewBuilder("support").
+		Param("question", "string").
+		Message(SystemRole, "You are a support agent").
+		History().
+		Message(UserRole, "{{ question }}").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []MessageTemplate{
+		{Role: SystemRole, Content: "You are a support agent"},
+		{Role: HistoryRole},
+		{Role: UserRole, Content: "{{ question }}"},
+	}
+	if !reflect.DeepEqual(promptFile.Messages, expected) {
+		t.Errorf("Expected messages %+v, got %+v", expected, promptFile.Messages)
+	}
+}
+
+func TestBuilder_Build_MatchesLoadedPromptFile(t *testing.T) {
+	loaded, err := NewPromptFileFromFile("test-data/with-tools.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := NewBuilder(loaded.Name).
+		Model(loaded.Model).
+		Param("city", "string").
+		System(loaded.Prompts.System).
+		User(loaded.Prompts.User)
+	for _, tool := range loaded.Tools {
+		builder.Tool(tool)
+	}
+
+	built, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded.Source = SourceMetadata{}
+	if !reflect.DeepEqual(built, loaded) {
+		t.Errorf("Expected built prompt file %+v, got %+v", loaded, built)
+	}
+}
+
+func TestBuilder_Build_ReturnsIndependentPromptFiles(t *testing.T) {
+	builder := NewBuilder("independent").Temperature(0.5).Param("topic", "string").User("{{ topic }}")
+
+	first, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	*first.Config.Temperature = 1
+	first.Config.Input.Parameters["extra"] = "string"
+
+	second, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *second.Config.Temperature != 0.5 {
+		t.Errorf("Expected temperature to be 0.5, got %v", *second.Config.Temperature)
+	}
+
+	if _, ok := second.Config.Input.Parameters["extra"]; ok {
+		t.Error("Expected changes to the first prompt file to not affect the builder")
+	}
+}
+
+func TestBuilder_Build_WithInvalidPromptFile_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		builder       *Builder
+		expectedError string
+	}{
+		{"missing-user", NewBuilder("test").System("system"), "no user prompt template was provided in the prompt file"},
+		{"empty-name", NewBuilder("!!!").User("user"), "the prompt file name, once cleaned, is empty"},
+		{"invalid-type", NewBuilder("test").Param("topic", "text").User("user"), "invalid data type for parameter topic: text"},
+		{"default-without-param", NewBuilder("test").Default("value").User("user"), "a default value was provided before any parameters were added"},
+		{"time-zone-without-param", NewBuilder("test").ParamTimeZone("UTC").User("user"), "a time zone was provided before any parameters were added"},
+		{"invalid-role", NewBuilder("test").Message("narrator", "Hello"), "invalid role for message 0: narrator"},
+		{"invalid-tool", NewBuilder("test").User("user").Tool(Tool{Name: "get weather"}), "invalid tool name: get weather"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := test.builder.Build()
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestBuilder_Build_WithOverride(t *testing.T) {
+	temperature := float32(0.1)
+	promptFile, err := NewBuilder("overrides").
+		Model("gpt-4o-mini").
+		User("Hello").
+		Override("prod", ProfileOverride{Model: "gpt-4o", Config: ConfigOverride{Temperature: &temperature}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := promptFile.ResolveProfile("prod")
+	if resolved.Model != "gpt-4o" || *resolved.Config.Temperature != 0.1 {
+		t.Errorf("Expected the prod override to be applied, got model '%s' and temperature %v", resolved.Model, resolved.Config.Temperature)
+	}
+}
+
+func TestBuilder_Build_WithTimeZones(t *testing.T) {
+	promptFile, err := NewBuilder("time-zones").
+		TimeZone("Europe/London").
+		DateLayouts("2006-01-02 15:04").
+		Param("meeting", "datetime").
+		ParamTimeZone("America/New_York").
+		User(`{{ meeting | date: "%H:%M" }}`).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := promptFile.GetUserPrompt(map[string]interface{}{"meeting": "2024-01-15 14:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prompt != "14:00" {
+		t.Errorf("Expected prompt '14:00', got '%s'", prompt)
+	}
+
+	if promptFile.Config.Input.TimeZones["meeting"] != "America/New_York" {
+		t.Errorf("Expected the meeting time zone to be 'America/New_York', got %v", promptFile.Config.Input.TimeZones)
+	}
+}
+
+func TestBuilder_Build_WithCoercion(t *testing.T) {
+	promptFile, err := NewBuilder("coercion").
+		Coerce(true).
+		Param("count", "number").
+		User("{{ count | plus: 1 }}").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := promptFile.GetUserPrompt(map[string]interface{}{"count": "41"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prompt != "42" {
+		t.Errorf("Expected prompt '42', got '%s'", prompt)
+	}
+}
+
+// ExampleNewBuilder demonstrates building a prompt file in code and then passing in values to the template to
+// generate the user prompt.
+func ExampleNewBuilder() {
+	promptFile, err := NewBuilder("holiday").
+		Model("gpt-4o").
+		Temperature(0.7).
+		Param("country", "string").
+		Default("Malta").
+		User("Tell me about {{ country }}").
+		Build()
+	if err != nil {
+		panic(err)
+	}
+
+	prompt, err := promptFile.GetUserPrompt(nil)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(prompt)
+	// Output: Tell me about Malta
+}
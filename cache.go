@@ -0,0 +1,444 @@
+package dotprompt
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cacheFileExtension string = ".response"
+
+// cacheKeyVersion is included in every cache key, and must be incremented whenever the content of cacheKeyContent
+// changes so that responses cached under the old keys are not reused.
+const cacheKeyVersion int = 1
+
+// CacheError represents an error encountered when reading from or writing to a response cache.
+// It contains a message describing the error and an optional underlying error.
+type CacheError struct {
+	Message string
+	Err     error
+}
+
+// Error returns the error message contained in the CacheError.
+func (e CacheError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e CacheError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseCache defines an interface for storing and retrieving model responses by cache key.
+type ResponseCache interface {
+
+	// Get returns the response stored for the key and a boolean indicating if a live entry was found.
+	Get(key string) (string, bool, error)
+
+	// Set stores the response against the key, replacing any existing entry.
+	Set(key string, response string) error
+}
+
+// ModelClient defines an interface for a client which sends a prompt file, rendered using the provided values, to a
+// model and returns the model's response.
+type ModelClient interface {
+
+	// Generate renders the prompt file with the values, calls the model, and returns the response.
+	Generate(promptFile *PromptFile, values map[string]interface{}) (string, error)
+}
+
+// CachingClient is a ModelClient which sits in front of another ModelClient and returns cached responses for
+// requests which have been seen before.
+type CachingClient struct {
+	client ModelClient
+	cache  ResponseCache
+}
+
+// NewCachingClient creates a new CachingClient which uses the cache to avoid repeated calls to the client.
+func NewCachingClient(client ModelClient, cache ResponseCache) (*CachingClient, error) {
+	if client == nil {
+		return nil, &PromptError{
+			Message: "client cannot be nil",
+		}
+	}
+
+	if cache == nil {
+		return nil, &PromptError{
+			Message: "cache cannot be nil",
+		}
+	}
+
+	return &CachingClient{client: client, cache: cache}, nil
+}
+
+// Generate returns the cached response for the prompt file and values if one exists, otherwise it calls the
+// underlying client and caches the result. Prompt files which set `cache: false` always call the client.
+func (c *CachingClient) Generate(promptFile *PromptFile, values map[string]interface{}) (string, error) {
+	if promptFile == nil {
+		return "", &PromptError{
+			Message: "prompt file cannot be nil",
+		}
+	}
+
+	if !promptFile.CacheEnabled() {
+		return c.client.Generate(promptFile, values)
+	}
+
+	key, err := promptFile.CacheKey(values)
+	if err != nil {
+		return "", err
+	}
+
+	if response, ok, getErr := c.cache.Get(key); getErr != nil {
+		return "", getErr
+	} else if ok {
+		return response, nil
+	}
+
+	response, err := c.client.Generate(promptFile, values)
+	if err != nil {
+		return "", err
+	}
+
+	if err = c.cache.Set(key, response); err != nil {
+		return "", err
+	}
+
+	return response, nil
+}
+
+// CacheEnabled returns true unless the prompt file has opted out of response caching with `cache: false`.
+func (pf *PromptFile) CacheEnabled() bool {
+	return pf.Config.Cache == nil || *pf.Config.Cache
+}
+
+// cacheKeyContent represents the model settings and rendered messages which are hashed to create a cache key. It is
+// kept separate from the exported types so that changes to them do not silently change the cache keys.
+type cacheKeyContent struct {
+	KeyVersion   int                    `json:"keyVersion"`
+	Model        string                 `json:"model"`
+	Temperature  *float32               `json:"temperature"`
+	MaxTokens    *int                   `json:"maxTokens"`
+	OutputFormat string                 `json:"outputFormat"`
+	Messages     []cacheKeyMessage      `json:"messages"`
+	Tools        []cacheKeyTool         `json:"tools"`
+	Parameters   map[string]interface{} `json:"parameters"`
+}
+
+// cacheKeyMessage represents a rendered message within a cache key.
+type cacheKeyMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	Parts      []cacheKeyPart `json:"parts"`
+	Name       string         `json:"name"`
+	ToolCallID string         `json:"toolCallId"`
+}
+
+// cacheKeyPart represents a part of a rendered message within a cache key.
+type cacheKeyPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"`
+}
+
+// cacheKeyTool represents a declared tool within a cache key.
+type cacheKeyTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// CacheKey generates a stable key for the prompt file when rendered with the provided values. The key is a SHA-256
+// hash of the model, temperature, max tokens, output format, the rendered message list, any declared tools, and any
+// other model parameters.
+func (pf *PromptFile) CacheKey(values map[string]interface{}) (string, error) {
+	messages, err := pf.GetMessages(values, nil)
+	if err != nil {
+		return "", err
+	}
+
+	parameters := pf.additionalModelParameters()
+	maps.Copy(parameters, pf.Config.Extra)
+
+	keyContent := cacheKeyContent{
+		KeyVersion:   cacheKeyVersion,
+		Model:        pf.Model,
+		Temperature:  pf.Config.Temperature,
+		MaxTokens:    pf.Config.MaxTokens,
+		OutputFormat: pf.Config.OutputFormat.String(),
+		Messages:     make([]cacheKeyMessage, 0, len(messages)),
+		Tools:        make([]cacheKeyTool, 0, len(pf.Tools)),
+		Parameters:   parameters,
+	}
+
+	for _, message := range messages {
+		keyMessage := cacheKeyMessage{
+			Role:       message.Role,
+			Content:    message.Content,
+			Parts:      make([]cacheKeyPart, 0, len(message.Parts)),
+			Name:       message.Name,
+			ToolCallID: message.ToolCallID,
+		}
+		for _, part := range message.Parts {
+			keyMessage.Parts = append(keyMessage.Parts, cacheKeyPart{
+				Type:     string(part.Type),
+				Text:     part.Text,
+				Name:     part.Name,
+				MimeType: part.MimeType,
+				Data:     part.Data,
+			})
+		}
+		keyContent.Messages = append(keyContent.Messages, keyMessage)
+	}
+
+	for _, tool := range pf.Tools {
+		keyContent.Tools = append(keyContent.Tools, cacheKeyTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Parameters,
+		})
+	}
+
+	content, err := json.Marshal(keyContent)
+	if err != nil {
+		return "", &PromptError{
+			Message: fmt.Sprintf("failed to generate cache key: %v", err),
+		}
+	}
+
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// memoryCacheEntry represents a single response held by a MemoryCache.
+type memoryCacheEntry struct {
+	key      string
+	response string
+	expires  time.Time
+}
+
+// MemoryCache is an in-memory ResponseCache which evicts the least recently used entries once it reaches capacity.
+// It is safe for concurrent use.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+// NewMemoryCache creates a new MemoryCache which holds up to capacity entries, each of which expires after ttl. A ttl
+// of zero means entries never expire.
+func NewMemoryCache(capacity int, ttl time.Duration) (*MemoryCache, error) {
+	if capacity <= 0 {
+		return nil, &CacheError{
+			Message: "the cache capacity must be greater than zero",
+		}
+	}
+
+	if ttl < 0 {
+		return nil, &CacheError{
+			Message: "the cache ttl cannot be negative",
+		}
+	}
+
+	return &MemoryCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}, nil
+}
+
+// Get returns the response stored for the key, marking it as the most recently used entry. Expired entries are
+// removed and reported as missing.
+func (c *MemoryCache) Get(key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return "", false, nil
+	}
+
+	entry := element.Value.(*memoryCacheEntry)
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return "", false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry.response, true, nil
+}
+
+// Set stores the response against the key, evicting the least recently used entry if the cache is full.
+func (c *MemoryCache) Set(key string, response string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = c.now().Add(c.ttl)
+	}
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*memoryCacheEntry)
+		entry.response = response
+		entry.expires = expires
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryCacheEntry{key: key, response: response, expires: expires})
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries currently held by the cache, including any which have expired but not yet been
+// removed.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// DirectoryCache is a ResponseCache which stores each response as a file in a directory on disk, so that cached
+// responses survive between runs.
+type DirectoryCache struct {
+	path string
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewDirectoryCache creates a new DirectoryCache which stores responses in the specified directory, creating it if it
+// does not exist. Entries expire after ttl, and a ttl of zero means entries never expire.
+func NewDirectoryCache(path string, ttl time.Duration) (*DirectoryCache, error) {
+	trimmedPath := strings.TrimSpace(path)
+	if trimmedPath == "" {
+		return nil, &CacheError{
+			Message: "The specified path is empty",
+		}
+	}
+
+	if ttl < 0 {
+		return nil, &CacheError{
+			Message: "the cache ttl cannot be negative",
+		}
+	}
+
+	if err := os.MkdirAll(trimmedPath, 0700); err != nil {
+		return nil, &CacheError{
+			Message: fmt.Sprintf("failed to create cache directory: %v", err),
+			Err:     err,
+		}
+	}
+
+	return &DirectoryCache{path: trimmedPath, ttl: ttl, now: time.Now}, nil
+}
+
+// Get returns the response stored for the key. Entries older than the cache ttl are removed and reported as missing.
+func (c *DirectoryCache) Get(key string) (string, bool, error) {
+	entryPath, err := c.entryPath(key)
+	if err != nil {
+		return "", false, err
+	}
+
+	info, err := os.Stat(entryPath)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, &CacheError{
+			Message: fmt.Sprintf("failed to read cache entry: %v", err),
+			Err:     err,
+		}
+	}
+
+	if c.ttl > 0 && !c.now().Before(info.ModTime().Add(c.ttl)) {
+		if removeErr := os.Remove(entryPath); removeErr != nil && !os.IsNotExist(removeErr) {
+			return "", false, &CacheError{
+				Message: fmt.Sprintf("failed to remove expired cache entry: %v", removeErr),
+				Err:     removeErr,
+			}
+		}
+		return "", false, nil
+	}
+
+	content, err := os.ReadFile(entryPath)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, &CacheError{
+			Message: fmt.Sprintf("failed to read cache entry: %v", err),
+			Err:     err,
+		}
+	}
+
+	return string(content), true, nil
+}
+
+// Set writes the response to the directory against the key. The entry is written to a temporary file first so that
+// concurrent readers never see a partially written response.
+func (c *DirectoryCache) Set(key string, response string) error {
+	entryPath, err := c.entryPath(key)
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(c.path, "tmp-*")
+	if err != nil {
+		return &CacheError{
+			Message: fmt.Sprintf("failed to write cache entry: %v", err),
+			Err:     err,
+		}
+	}
+
+	_, writeErr := tempFile.WriteString(response)
+	closeErr := tempFile.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tempFile.Name(), entryPath)
+	}
+	if writeErr != nil {
+		_ = os.Remove(tempFile.Name())
+		return &CacheError{
+			Message: fmt.Sprintf("failed to write cache entry: %v", writeErr),
+			Err:     writeErr,
+		}
+	}
+
+	return nil
+}
+
+// entryPath returns the path of the file used to store the entry for the key, rejecting keys which could escape the
+// cache directory.
+func (c *DirectoryCache) entryPath(key string) (string, error) {
+	if len(key) == 0 || strings.ContainsAny(key, `/\.`) {
+		return "", &CacheError{
+			Message: fmt.Sprintf("invalid cache key: %s", key),
+		}
+	}
+
+	return filepath.Join(c.path, key+cacheFileExtension), nil
+}
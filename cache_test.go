@@ -0,0 +1,424 @@
+package dotprompt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type MockModelClient struct {
+	Response  string
+	Err       error
+	CallCount int
+}
+
+func (m *MockModelClient) Generate(_ *PromptFile, _ map[string]interface{}) (string, error) {
+	m.CallCount++
+	return m.Response, m.Err
+}
+
+func TestPromptFile_CacheKey_IsStable(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := promptFile.CacheKey(map[string]interface{}{"country": "Italy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := promptFile.CacheKey(map[string]interface{}{"country": "Italy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Errorf("Expected cache keys to match, got '%s' and '%s'", first, second)
+	}
+
+	if len(first) != 64 {
+		t.Errorf("Expected cache key to be a 64 character hash, got '%s'", first)
+	}
+}
+
+func TestPromptFile_CacheKey_IsUnchanged(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := promptFile.CacheKey(map[string]interface{}{"country": "Italy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// If this key changes then cacheKeyVersion must be incremented, and the expected key updated
+	expected := "b3ae816315e2cdc3ce26fc680b7b46a481c4bc9209c069224945aa3a9ff58df0"
+	if key != expected {
+		t.Errorf("Expected cache key '%s', got '%s'", expected, key)
+	}
+}
+
+func TestPromptFile_CacheKey_ChangesWithInputs(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseKey, err := promptFile.CacheKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	temperature := float32(0.1)
+	maxTokens := 10
+
+	tests := []struct {
+		name   string
+		modify func(pf *PromptFile)
+		values map[string]interface{}
+	}{
+		{"values", func(pf *PromptFile) {}, map[string]interface{}{"country": "Italy"}},
+		{"model", func(pf *PromptFile) { pf.Model = "gpt-4o" }, nil},
+		{"temperature", func(pf *PromptFile) { pf.Config.Temperature = &temperature }, nil},
+		{"max-tokens", func(pf *PromptFile) { pf.Config.MaxTokens = &maxTokens }, nil},
+		{"output-format", func(pf *PromptFile) { pf.Config.OutputFormat = Json }, nil},
+		{"few-shots", func(pf *PromptFile) { pf.FewShots = []FewShotPromptPair{{User: "a", Response: "b"}} }, nil},
+		{"tools", func(pf *PromptFile) { pf.Tools = []Tool{{Name: "a"}} }, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			modified := *promptFile
+			test.modify(&modified)
+
+			key, err := modified.CacheKey(test.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if key == baseKey {
+				t.Errorf("Expected cache key to change, got '%s'", key)
+			}
+		})
+	}
+}
+
+func TestPromptFile_CacheEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"default", "prompts:\n  user: User prompt", true},
+		{"enabled", "config:\n  cache: true\nprompts:\n  user: User prompt", true},
+		{"disabled", "config:\n  cache: false\nprompts:\n  user: User prompt", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			promptFile, err := NewPromptFile(test.name, []byte(test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if promptFile.CacheEnabled() != test.expected {
+				t.Errorf("Expected cache enabled to be %v, got %v", test.expected, promptFile.CacheEnabled())
+			}
+		})
+	}
+}
+
+func TestCachingClient_Generate(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewMemoryCache(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &MockModelClient{Response: "response"}
+	cachingClient, err := NewCachingClient(client, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		response, err := cachingClient.Generate(promptFile, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response != "response" {
+			t.Errorf("Expected response to be 'response', got '%s'", response)
+		}
+	}
+
+	if client.CallCount != 1 {
+		t.Errorf("Expected client to be called once, got %d", client.CallCount)
+	}
+
+	if _, err = cachingClient.Generate(promptFile, map[string]interface{}{"country": "Italy"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if client.CallCount != 2 {
+		t.Errorf("Expected client to be called twice, got %d", client.CallCount)
+	}
+}
+
+func TestCachingClient_Generate_WithCacheDisabled(t *testing.T) {
+	promptFile, err := NewPromptFile("no-cache", []byte("config:\n  cache: false\nprompts:\n  user: User prompt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewMemoryCache(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &MockModelClient{Response: "response"}
+	cachingClient, err := NewCachingClient(client, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err = cachingClient.Generate(promptFile, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if client.CallCount != 2 {
+		t.Errorf("Expected client to be called twice, got %d", client.CallCount)
+	}
+
+	if cache.Len() != 0 {
+		t.Errorf("Expected cache to be empty, got %d entries", cache.Len())
+	}
+}
+
+func TestCachingClient_Generate_WithClientError_DoesNotCache(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewMemoryCache(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &MockModelClient{Err: errors.New("model unavailable")}
+	cachingClient, err := NewCachingClient(client, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cachingClient.Generate(promptFile, nil); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+
+	if cache.Len() != 0 {
+		t.Errorf("Expected cache to be empty, got %d entries", cache.Len())
+	}
+}
+
+func TestCachingClient_Generate_WithNilPromptFile_ReturnsError(t *testing.T) {
+	cache, err := NewMemoryCache(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cachingClient, err := NewCachingClient(&MockModelClient{}, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cachingClient.Generate(nil, nil)
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expectedError := "prompt file cannot be nil"
+	if promptError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, promptError.Error())
+	}
+}
+
+func TestNewCachingClient_WithNilArguments_ReturnsError(t *testing.T) {
+	cache, err := NewMemoryCache(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewCachingClient(nil, cache); err == nil {
+		t.Error("Expected error for nil client, got none")
+	}
+
+	if _, err = NewCachingClient(&MockModelClient{}, nil); err == nil {
+		t.Error("Expected error for nil cache, got none")
+	}
+}
+
+func TestNewMemoryCache_WithInvalidArguments(t *testing.T) {
+	tests := []struct {
+		name          string
+		capacity      int
+		ttl           time.Duration
+		expectedError string
+	}{
+		{"zero-capacity", 0, 0, "the cache capacity must be greater than zero"},
+		{"negative-ttl", 1, -time.Second, "the cache ttl cannot be negative"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewMemoryCache(test.capacity, test.ttl)
+
+			var cacheError *CacheError
+			if !errors.As(err, &cacheError) {
+				t.Fatalf("Expected CacheError, got %T", err)
+			}
+
+			if cacheError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, cacheError.Error())
+			}
+		})
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := NewMemoryCache(2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = cache.Set("a", "1")
+	_ = cache.Set("b", "2")
+
+	// Reading "a" makes "b" the least recently used entry
+	if _, ok, _ := cache.Get("a"); !ok {
+		t.Fatal("Expected entry 'a' to be cached")
+	}
+
+	_ = cache.Set("c", "3")
+
+	if _, ok, _ := cache.Get("b"); ok {
+		t.Error("Expected entry 'b' to be evicted")
+	}
+
+	if response, ok, _ := cache.Get("a"); !ok || response != "1" {
+		t.Errorf("Expected entry 'a' to be '1', got '%s'", response)
+	}
+
+	if response, ok, _ := cache.Get("c"); !ok || response != "3" {
+		t.Errorf("Expected entry 'c' to be '3', got '%s'", response)
+	}
+}
+
+func TestMemoryCache_ExpiresEntries(t *testing.T) {
+	cache, err := NewMemoryCache(2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	_ = cache.Set("a", "1")
+
+	now = now.Add(59 * time.Second)
+	if _, ok, _ := cache.Get("a"); !ok {
+		t.Fatal("Expected entry to be live")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok, _ := cache.Get("a"); ok {
+		t.Fatal("Expected entry to have expired")
+	}
+
+	if cache.Len() != 0 {
+		t.Errorf("Expected expired entry to be removed, got %d entries", cache.Len())
+	}
+}
+
+func TestDirectoryCache_SetAndGet(t *testing.T) {
+	cache, err := NewDirectoryCache(filepath.Join(t.TempDir(), "cache"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := cache.Get("missing"); err != nil || ok {
+		t.Fatalf("Expected missing entry, got ok=%v err=%v", ok, err)
+	}
+
+	if err = cache.Set("key", "response"); err != nil {
+		t.Fatal(err)
+	}
+
+	response, ok, err := cache.Get("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok || response != "response" {
+		t.Errorf("Expected cached response 'response', got '%s'", response)
+	}
+}
+
+func TestDirectoryCache_ExpiresEntries(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDirectoryCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cache.Set("key", "response"); err != nil {
+		t.Fatal(err)
+	}
+
+	cache.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	if _, ok, err := cache.Get("key"); err != nil || ok {
+		t.Fatalf("Expected entry to have expired, got ok=%v err=%v", ok, err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "key"+cacheFileExtension)); !os.IsNotExist(err) {
+		t.Error("Expected expired entry to be removed from disk")
+	}
+}
+
+func TestDirectoryCache_WithInvalidKey_ReturnsError(t *testing.T) {
+	cache, err := NewDirectoryCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cache.Set("../escape", "response")
+
+	var cacheError *CacheError
+	if !errors.As(err, &cacheError) {
+		t.Fatalf("Expected CacheError, got %T", err)
+	}
+}
+
+func TestNewDirectoryCache_WithEmptyPath_ReturnsError(t *testing.T) {
+	_, err := NewDirectoryCache(" ", 0)
+
+	var cacheError *CacheError
+	if !errors.As(err, &cacheError) {
+		t.Fatalf("Expected CacheError, got %T", err)
+	}
+}
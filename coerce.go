@@ -0,0 +1,109 @@
+package dotprompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// coerceParameter converts the value of a parameter to the declared type when the value has a compatible type, such as
+// the string "42" for a number or "true" for a bool. Values which already have the declared type, and parameters of
+// types which are not coerced, are returned unchanged. Returns an error if the value cannot be converted.
+func coerceParameter(parameter string, parameterType string, value interface{}) (interface{}, error) {
+	var coerced interface{}
+	var err error
+
+	switch parameterType {
+	case "string":
+		coerced, err = coerceString(value)
+	case "number":
+		coerced, err = coerceNumber(value)
+	case "bool":
+		coerced, err = coerceBool(value)
+	default:
+		return value, nil
+	}
+
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s cannot be coerced to a %s: %v", parameter, parameterType, err),
+		}
+	}
+	return coerced, nil
+}
+
+// coerceString converts numbers, bools, and json.Number values to their string representation. Other types, including
+// those which implement fmt.Stringer, are rejected so that structured values are not rendered by accident.
+func coerceString(value interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, nil
+	case json.Number:
+		return typedValue.String(), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(typedValue), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// coerceNumber converts json.Number values and strings to an int64 when they hold a whole number within its range, and
+// to a float64 otherwise. Unsigned integers too large for an int64 are converted to a float64, losing precision, and
+// other numeric values are returned unchanged.
+func coerceNumber(value interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case uint:
+		if uint64(typedValue) > math.MaxInt64 {
+			return float64(typedValue), nil
+		}
+	case uint64:
+		if typedValue > math.MaxInt64 {
+			return float64(typedValue), nil
+		}
+	}
+
+	if isNumeric(value) {
+		return value, nil
+	}
+
+	switch typedValue := value.(type) {
+	case json.Number:
+		return parseNumber(typedValue.String())
+	case string:
+		return parseNumber(strings.TrimSpace(typedValue))
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// parseNumber parses the text as an int64, or as a float64 if it is not a whole number within the range of an int64.
+// Returns an error if the text is not a finite number.
+func parseNumber(text string) (interface{}, error) {
+	if integer, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return integer, nil
+	}
+
+	float, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsInf(float, 0) || math.IsNaN(float) {
+		return nil, fmt.Errorf("%q is not a valid number", text)
+	}
+	return float, nil
+}
+
+// coerceBool converts strings such as "true", "false", "1", and "0" to a bool.
+func coerceBool(value interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case bool:
+		return typedValue, nil
+	case string:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(typedValue))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid bool", typedValue)
+		}
+		return parsed, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
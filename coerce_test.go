@@ -0,0 +1,218 @@
+package dotprompt
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"testing/fstest"
+)
+
+type coerceStringer struct{}
+
+func (coerceStringer) String() string {
+	return "Stringer"
+}
+
+func TestPromptFile_GetUserPrompt_WithCoercion(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/coerce.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		values   map[string]interface{}
+		expected string
+	}{
+		{
+			"native-types",
+			map[string]interface{}{"name": "Arthur", "count": 41, "enabled": true},
+			"Arthur has 42 items, enabled yes, limit 10",
+		},
+		{
+			"strings",
+			map[string]interface{}{"name": "Arthur", "count": " 41 ", "enabled": "false", "limit": "2.5"},
+			"Arthur has 42 items, enabled no, limit 2.5",
+		},
+		{
+			"json-number",
+			map[string]interface{}{"name": json.Number("42"), "count": json.Number("41"), "enabled": "1", "limit": json.Number("1.5")},
+			"42 has 42 items, enabled yes, limit 1.5",
+		},
+		{
+			"unsigned",
+			map[string]interface{}{"name": uint8(7), "count": uint64(41), "enabled": "T", "limit": uint(3)},
+			"7 has 42 items, enabled yes, limit 3",
+		},
+		{
+			"large-unsigned",
+			map[string]interface{}{"name": "Arthur", "count": uint32(41), "enabled": "TRUE", "limit": uint64(math.MaxUint64)},
+			"Arthur has 42 items, enabled yes, limit 1.8446744073709552e+19",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			prompt, err := promptFile.GetUserPrompt(test.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if prompt != test.expected {
+				t.Errorf("Expected prompt '%s', got '%s'", test.expected, prompt)
+			}
+		})
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithCoercion_ReturnsError(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/coerce.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		values        map[string]interface{}
+		expectedError string
+	}{
+		{
+			"invalid-number",
+			map[string]interface{}{"name": "Arthur", "count": "forty two", "enabled": true},
+			`parameter count cannot be coerced to a number: "forty two" is not a valid number`,
+		},
+		{
+			"not-finite",
+			map[string]interface{}{"name": "Arthur", "count": "NaN", "enabled": true},
+			`parameter count cannot be coerced to a number: "NaN" is not a valid number`,
+		},
+		{
+			"invalid-json-number",
+			map[string]interface{}{"name": "Arthur", "count": json.Number("1e400"), "enabled": true},
+			`parameter count cannot be coerced to a number: "1e400" is not a valid number`,
+		},
+		{
+			"unsupported-number",
+			map[string]interface{}{"name": "Arthur", "count": true, "enabled": true},
+			"parameter count cannot be coerced to a number: unsupported type bool",
+		},
+		{
+			"invalid-bool",
+			map[string]interface{}{"name": "Arthur", "count": 1, "enabled": "maybe"},
+			`parameter enabled cannot be coerced to a bool: "maybe" is not a valid bool`,
+		},
+		{
+			"unsupported-bool",
+			map[string]interface{}{"name": "Arthur", "count": 1, "enabled": 1},
+			"parameter enabled cannot be coerced to a bool: unsupported type int",
+		},
+		{
+			"unsupported-string",
+			map[string]interface{}{"name": []string{"Arthur"}, "count": 1, "enabled": true},
+			"parameter name cannot be coerced to a string: unsupported type []string",
+		},
+		{
+			"unsupported-stringer",
+			map[string]interface{}{"name": coerceStringer{}, "count": 1, "enabled": true},
+			"parameter name cannot be coerced to a string: unsupported type dotprompt.coerceStringer",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := promptFile.GetUserPrompt(test.values)
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithoutCoercion_ReturnsError(t *testing.T) {
+	data := "config:\n  input:\n    parameters:\n      count: number\nprompts:\n  user: '{{ count }}'\n"
+	promptFile, err := NewPromptFile("without-coercion", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = promptFile.GetUserPrompt(map[string]interface{}{"count": "42"})
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	if promptError.Error() != "parameter count is not a number" {
+		t.Errorf("Expected error 'parameter count is not a number', got '%s'", promptError.Error())
+	}
+
+	// Unsigned integers too large for an int64 are only converted when coercion is enabled
+	_, err = promptFile.GetUserPrompt(map[string]interface{}{"count": uint64(math.MaxUint64)})
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	if promptError.Error() != "parameter count is out of range" {
+		t.Errorf("Expected error 'parameter count is out of range', got '%s'", promptError.Error())
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithCoercionOptions(t *testing.T) {
+	data := "config:\n  input:\n    parameters:\n      count: number\nprompts:\n  user: '{{ count | plus: 1 }}'\n"
+
+	fromOption, err := NewPromptFile("with-coercion", []byte(data), WithCoercion())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := NewFSStore(fstest.MapFS{
+		"with-coercion.prompt": {Data: []byte(data)},
+	}, WithPromptFileOptions(WithCoercion())).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewManagerFromLoader(NewFSStore(fstest.MapFS{
+		"with-coercion.prompt": {Data: []byte(data)},
+	}), WithParameterCoercion())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromManager, err := manager.GetPromptFile("with-coercionprompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		promptFile PromptFile
+	}{
+		{"prompt-file-option", *fromOption},
+		{"loader-option", promptFiles[0]},
+		{"manager-option", fromManager},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			prompt, err := test.promptFile.GetUserPrompt(map[string]interface{}{"count": "41"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if prompt != "42" {
+				t.Errorf("Expected prompt '42', got '%s'", prompt)
+			}
+		})
+	}
+}
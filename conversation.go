@@ -0,0 +1,201 @@
+package dotprompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// messageTokenOverhead is the approximate number of tokens used by each message in addition to its content.
+const messageTokenOverhead int = 4
+
+// TokenCounter returns the number of tokens in a piece of text.
+type TokenCounter func(text string) int
+
+// Summarizer condenses messages which have been trimmed from a conversation into a single message, which is
+// inserted in their place.
+type Summarizer func(messages []Message) (Message, error)
+
+// ConversationOption configures optional behaviour of a Conversation.
+type ConversationOption func(c *Conversation)
+
+// WithTokenCounter sets the function used to count the tokens in each message. By default, tokens are approximated
+// using ApproximateTokenCount.
+func WithTokenCounter(counter TokenCounter) ConversationOption {
+	return func(c *Conversation) {
+		c.tokenCounter = counter
+	}
+}
+
+// WithSummarizer sets the function used to summarize history which is trimmed to fit the token budget. By default,
+// trimmed history is discarded.
+func WithSummarizer(summarizer Summarizer) ConversationOption {
+	return func(c *Conversation) {
+		c.summarizer = summarizer
+	}
+}
+
+// ApproximateTokenCount estimates the number of tokens in a piece of text as one token for every four characters.
+func ApproximateTokenCount(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Conversation holds the history of a conversation which uses a PromptFile, and renders it into messages which fit
+// within a token budget. The system prompt and few-shot pairs of the prompt file are always included, with the
+// oldest history trimmed first. A Conversation can be serialized to JSON and restored using LoadConversation.
+type Conversation struct {
+	PromptName string    `json:"promptName"`
+	History    []Message `json:"history"`
+
+	promptFile   *PromptFile
+	tokenCounter TokenCounter
+	summarizer   Summarizer
+}
+
+// NewConversation creates a new Conversation with an empty history for the prompt file.
+func NewConversation(promptFile *PromptFile, options ...ConversationOption) (*Conversation, error) {
+	if promptFile == nil {
+		return nil, &PromptError{
+			Message: "prompt file cannot be nil",
+		}
+	}
+
+	conversation := &Conversation{
+		PromptName:   promptFile.Name,
+		History:      make([]Message, 0),
+		promptFile:   promptFile,
+		tokenCounter: ApproximateTokenCount,
+	}
+
+	for _, option := range options {
+		option(conversation)
+	}
+
+	if conversation.tokenCounter == nil {
+		return nil, &PromptError{
+			Message: "token counter cannot be nil",
+		}
+	}
+
+	return conversation, nil
+}
+
+// LoadConversation restores a Conversation which was serialized to JSON, attaching it to the prompt file. Returns an
+// error if the conversation was created for a different prompt file.
+func LoadConversation(promptFile *PromptFile, data []byte, options ...ConversationOption) (*Conversation, error) {
+	conversation, err := NewConversation(promptFile, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, conversation); err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("failed to parse conversation: %v", err),
+		}
+	}
+
+	if conversation.PromptName != promptFile.Name {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("conversation is for prompt file %s, not %s", conversation.PromptName, promptFile.Name),
+		}
+	}
+
+	return conversation, nil
+}
+
+// AddUserMessage appends a user turn to the conversation history.
+func (c *Conversation) AddUserMessage(content string) {
+	c.Append(Message{Role: UserRole, Content: content})
+}
+
+// AddAssistantMessage appends an assistant turn to the conversation history.
+func (c *Conversation) AddAssistantMessage(content string) {
+	c.Append(Message{Role: AssistantRole, Content: content})
+}
+
+// Append adds a message to the end of the conversation history.
+func (c *Conversation) Append(message Message) {
+	c.History = append(c.History, message)
+}
+
+// Render renders the prompt file with the values and conversation history into messages which fit within maxTokens.
+// If the full history does not fit, the oldest turns are trimmed, starting each time from a user message, and are
+// replaced with a summary if a Summarizer has been set. The summarizer is called once, and if the summary does not fit
+// alongside the remaining history, further turns are trimmed, or the summary is left out if only the latest turn
+// fits. Returns an error if not even the latest turn of the history fits. The history held by the conversation is not
+// modified.
+func (c *Conversation) Render(values map[string]interface{}, maxTokens int) ([]Message, error) {
+	if c.promptFile == nil {
+		return nil, &PromptError{
+			Message: "conversation does not have a prompt file, use NewConversation or LoadConversation",
+		}
+	}
+
+	messages, err := c.promptFile.GetMessages(values, c.History)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.countTokens(messages) <= maxTokens {
+		return messages, nil
+	}
+
+	base, err := c.promptFile.GetMessages(values, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	available := maxTokens - c.countTokens(base)
+	if available < 0 {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("the prompt requires more than %d tokens without any history", maxTokens),
+		}
+	}
+
+	start := c.nextTurn(0, available, 0)
+	if start == len(c.History) {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("the latest turn of the conversation does not fit within %d tokens", maxTokens),
+		}
+	}
+
+	kept := c.History[start:]
+	if c.summarizer != nil {
+		summary, summaryErr := c.summarizer(c.History[:start])
+		if summaryErr != nil {
+			return nil, summaryErr
+		}
+
+		summaryTokens := c.countTokens([]Message{summary})
+		if summaryStart := c.nextTurn(start-1, available, summaryTokens); summaryStart < len(c.History) {
+			kept = append([]Message{summary}, c.History[summaryStart:]...)
+		}
+	}
+
+	return c.promptFile.GetMessages(values, kept)
+}
+
+// nextTurn returns the index of the first user message following after from which the rest of the history, together
+// with the reserved tokens, fits within the available tokens. Returns the length of the history if no such message exists.
+func (c *Conversation) nextTurn(after int, available int, reserved int) int {
+	for start := after + 1; start < len(c.History); start++ {
+		if c.History[start].Role != UserRole {
+			continue
+		}
+
+		if c.countTokens(c.History[start:])+reserved <= available {
+			return start
+		}
+	}
+
+	return len(c.History)
+}
+
+// countTokens returns the approximate number of tokens used by the messages.
+func (c *Conversation) countTokens(messages []Message) int {
+	total := 0
+	for _, message := range messages {
+		total += c.tokenCounter(message.Text()) + messageTokenOverhead
+	}
+	return total
+}
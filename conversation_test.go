@@ -0,0 +1,326 @@
+package dotprompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+const conversationPrompt = `name: chat
+config:
+  input:
+    parameters:
+      question: string
+prompts:
+  system: You are helpful
+  user: "{{ question }}"
+fewShots:
+  - user: Hi
+    response: Hello
+`
+
+// oneTokenPerMessage counts every message as a single token, so that with the per-message overhead each message
+// costs five tokens.
+func oneTokenPerMessage(_ string) int {
+	return 1
+}
+
+func newTestConversation(t *testing.T, options ...ConversationOption) *Conversation {
+	t.Helper()
+
+	promptFile, err := NewPromptFile("chat", []byte(conversationPrompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options = append([]ConversationOption{WithTokenCounter(oneTokenPerMessage)}, options...)
+	conversation, err := NewConversation(promptFile, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		conversation.AddUserMessage(fmt.Sprintf("Question %d", i))
+		conversation.AddAssistantMessage(fmt.Sprintf("Answer %d", i))
+	}
+
+	return conversation
+}
+
+func contents(messages []Message) []string {
+	result := make([]string, 0, len(messages))
+	for _, message := range messages {
+		result = append(result, message.Content)
+	}
+	return result
+}
+
+func TestConversation_Render_WithinBudget(t *testing.T) {
+	conversation := newTestConversation(t)
+
+	messages, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"You are helpful", "Hi", "Hello", "Question 1", "Answer 1", "Question 2", "Answer 2", "Question 3", "Answer 3", "Question 4"}
+	if !reflect.DeepEqual(contents(messages), expected) {
+		t.Errorf("Expected messages %v, got %v", expected, contents(messages))
+	}
+}
+
+func TestConversation_Render_TrimsOldestTurns(t *testing.T) {
+	conversation := newTestConversation(t)
+
+	// The system prompt, few-shots, and user prompt use 20 tokens, leaving room for 4 history messages
+	messages, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 44)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"You are helpful", "Hi", "Hello", "Question 2", "Answer 2", "Question 3", "Answer 3", "Question 4"}
+	if !reflect.DeepEqual(contents(messages), expected) {
+		t.Errorf("Expected messages %v, got %v", expected, contents(messages))
+	}
+
+	if len(conversation.History) != 6 {
+		t.Errorf("Expected history to be unchanged, got %d messages", len(conversation.History))
+	}
+}
+
+func TestConversation_Render_TrimsToUserTurnBoundary(t *testing.T) {
+	conversation := newTestConversation(t)
+
+	// Room for 3 history messages, but trimming must start at a user message so only 2 are kept
+	messages, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 39)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"You are helpful", "Hi", "Hello", "Question 3", "Answer 3", "Question 4"}
+	if !reflect.DeepEqual(contents(messages), expected) {
+		t.Errorf("Expected messages %v, got %v", expected, contents(messages))
+	}
+}
+
+func TestConversation_Render_WithSummarizer(t *testing.T) {
+	summarizedCount := 0
+	calls := 0
+	conversation := newTestConversation(t, WithSummarizer(func(messages []Message) (Message, error) {
+		summarizedCount = len(messages)
+		calls++
+		return Message{Role: SystemRole, Content: "Summary"}, nil
+	}))
+
+	// Room for 5 history messages, so the summary fits alongside the 4 most recent
+	messages, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 49)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"You are helpful", "Hi", "Hello", "Summary", "Question 2", "Answer 2", "Question 3", "Answer 3", "Question 4"}
+	if !reflect.DeepEqual(contents(messages), expected) {
+		t.Errorf("Expected messages %v, got %v", expected, contents(messages))
+	}
+
+	if summarizedCount != 2 {
+		t.Errorf("Expected 2 messages to be summarized, got %d", summarizedCount)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the summarizer to be called once, got %d", calls)
+	}
+}
+
+func TestConversation_Render_WithSummarizer_TrimsToFitSummary(t *testing.T) {
+	calls := 0
+	conversation := newTestConversation(t, WithSummarizer(func(messages []Message) (Message, error) {
+		calls++
+		return Message{Role: SystemRole, Content: "Summary"}, nil
+	}))
+
+	// Room for 4 history messages, so a further turn is trimmed to make room for the summary
+	messages, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 44)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"You are helpful", "Hi", "Hello", "Summary", "Question 3", "Answer 3", "Question 4"}
+	if !reflect.DeepEqual(contents(messages), expected) {
+		t.Errorf("Expected messages %v, got %v", expected, contents(messages))
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the summarizer to be called once, got %d", calls)
+	}
+}
+
+func TestConversation_Render_WithSummarizer_OmitsSummaryWhichDoesNotFit(t *testing.T) {
+	conversation := newTestConversation(t, WithSummarizer(func(messages []Message) (Message, error) {
+		return Message{Role: SystemRole, Content: "Summary"}, nil
+	}))
+
+	// Room for only the latest turn, so the summary is left out
+	messages, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"You are helpful", "Hi", "Hello", "Question 3", "Answer 3", "Question 4"}
+	if !reflect.DeepEqual(contents(messages), expected) {
+		t.Errorf("Expected messages %v, got %v", expected, contents(messages))
+	}
+}
+
+func TestConversation_Render_WithSummarizerError_ReturnsError(t *testing.T) {
+	conversation := newTestConversation(t, WithSummarizer(func(messages []Message) (Message, error) {
+		return Message{}, errors.New("summary failed")
+	}))
+
+	if _, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 44); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+}
+
+func TestConversation_Render_WithNoRoomForHistory_ReturnsError(t *testing.T) {
+	conversation := newTestConversation(t)
+
+	_, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 29)
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expectedError := "the latest turn of the conversation does not fit within 29 tokens"
+	if promptError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, promptError.Error())
+	}
+}
+
+func TestConversation_Render_WithoutPromptFile_ReturnsError(t *testing.T) {
+	conversation := &Conversation{}
+
+	_, err := conversation.Render(nil, 100)
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+}
+
+func TestConversation_Render_WithBudgetTooSmall_ReturnsError(t *testing.T) {
+	conversation := newTestConversation(t)
+
+	_, err := conversation.Render(map[string]interface{}{"question": "Question 4"}, 19)
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expectedError := "the prompt requires more than 19 tokens without any history"
+	if promptError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, promptError.Error())
+	}
+}
+
+func TestConversation_JSONRoundTrip(t *testing.T) {
+	conversation := newTestConversation(t)
+
+	data, err := json.Marshal(conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := LoadConversation(conversation.promptFile, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(restored.History, conversation.History) {
+		t.Errorf("Expected history %+v, got %+v", conversation.History, restored.History)
+	}
+
+	if _, err = restored.Render(map[string]interface{}{"question": "Question 4"}, 100); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConversation_WithInvalidData_ReturnsError(t *testing.T) {
+	promptFile, err := NewPromptFile("chat", []byte(conversationPrompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		data          string
+		expectedError string
+	}{
+		{"invalid-json", `{`, "failed to parse conversation: unexpected end of JSON input"},
+		{"different-prompt", `{"promptName": "other", "history": []}`, "conversation is for prompt file other, not chat"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := LoadConversation(promptFile, []byte(test.data))
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestNewConversation_WithNilPromptFile_ReturnsError(t *testing.T) {
+	if _, err := NewConversation(nil); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+}
+
+func TestNewConversation_WithNilTokenCounter_ReturnsError(t *testing.T) {
+	promptFile, err := NewPromptFile("chat", []byte(conversationPrompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewConversation(promptFile, WithTokenCounter(nil))
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expectedError := "token counter cannot be nil"
+	if promptError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, promptError.Error())
+	}
+}
+
+func TestApproximateTokenCount(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"ĥéľľö wörld", 3},
+	}
+
+	for _, test := range tests {
+		if count := ApproximateTokenCount(test.text); count != test.expected {
+			t.Errorf("Expected '%s' to have %d tokens, got %d", test.text, test.expected, count)
+		}
+	}
+}
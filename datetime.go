@@ -0,0 +1,190 @@
+package dotprompt
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/osteele/liquid/values"
+	"github.com/osteele/tuesday"
+)
+
+// defaultDateFormat is the format used by the date filter when no format is given, which is the same as the default
+// of the Liquid date filter.
+const defaultDateFormat = "%a, %b %d, %y"
+
+// timeZone returns the time zone of the datetime parameter, which is the time zone set for the parameter, then the
+// time zone of the prompt file, and the local time zone if neither is set.
+func (pf *PromptFile) timeZone(parameter string) (*time.Location, error) {
+	name, ok := pf.Config.Input.TimeZones[parameter]
+	if !ok {
+		name = pf.Config.Input.TimeZone
+	}
+
+	if name == "" {
+		return time.Local, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("invalid time zone: %s", name),
+		}
+	}
+	return location, nil
+}
+
+// parseDatetimeParameter returns the value of the datetime parameter as a time.Time in the time zone of the parameter.
+// Strings are parsed as RFC 3339, then using each of the date layouts of the prompt file in turn, and strings without
+// a time zone offset are interpreted in the time zone of the parameter. Returns an error if the value is not a datetime.
+func (pf *PromptFile) parseDatetimeParameter(parameter string, value interface{}) (time.Time, error) {
+	location, err := pf.timeZone(parameter)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch typedValue := value.(type) {
+	case time.Time:
+		if !pf.hasTimeZone(parameter) {
+			return typedValue, nil
+		}
+		return typedValue.In(location), nil
+	case string:
+		if datetime, ok := pf.parseDatetime(typedValue, location); ok {
+			return datetime, nil
+		}
+	}
+
+	return time.Time{}, &PromptError{
+		Message: fmt.Sprintf("parameter %s is not a datetime", parameter),
+	}
+}
+
+// parseDatetime parses the string as RFC 3339, then using each of the date layouts of the prompt file in turn,
+// returning the time in the location. Strings without a time zone offset are interpreted in the location.
+func (pf *PromptFile) parseDatetime(value string, location *time.Location) (time.Time, bool) {
+	layouts := append([]string{time.RFC3339}, pf.Config.Input.DateLayouts...)
+	for _, layout := range layouts {
+		if datetime, err := time.ParseInLocation(layout, value, location); err == nil {
+			return datetime.In(location), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// hasTimeZone returns true if a time zone is set for the datetime parameter, either for the parameter itself or for the
+// prompt file.
+func (pf *PromptFile) hasTimeZone(parameter string) bool {
+	_, ok := pf.Config.Input.TimeZones[parameter]
+	return ok || pf.Config.Input.TimeZone != ""
+}
+
+// dateFilter formats a date using a strftime format in the same way as the Liquid date filter. Datetime parameters
+// have already been converted to their time zone, while strings, including "now", are interpreted and formatted in the
+// time zone of the prompt file. Other values are converted in the same way as the Liquid date filter, so nil, such as
+// an absent optional parameter, is formatted as the zero time.
+func (pf *PromptFile) dateFilter(value interface{}, format func(string) string) (string, error) {
+	var datetime time.Time
+
+	switch typedValue := value.(type) {
+	case nil:
+	case time.Time:
+		datetime = typedValue
+	case string:
+		parsed, err := pf.parseDateFilterString(typedValue)
+		if err != nil {
+			return "", err
+		}
+		datetime = parsed
+	default:
+		converted, err := values.Convert(value, reflect.TypeOf(datetime))
+		if err != nil {
+			return "", err
+		}
+		datetime = converted.(time.Time)
+	}
+
+	return tuesday.Strftime(format(defaultDateFormat), datetime)
+}
+
+// parseDateFilterString parses a string given to the date filter in the time zone of the prompt file, using the
+// layouts of the prompt file and then those of the Liquid date filter.
+func (pf *PromptFile) parseDateFilterString(text string) (time.Time, error) {
+	location, err := pf.timeZone("")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if text == "now" {
+		return time.Now().In(location), nil
+	}
+
+	if datetime, ok := pf.parseDatetime(text, location); ok {
+		return datetime, nil
+	}
+
+	datetime, err := values.ParseDate(text)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// ParseDate interprets strings without a time zone offset in the local time zone
+	if datetime.Location() == time.Local {
+		year, month, day := datetime.Date()
+		hour, minute, second := datetime.Clock()
+		datetime = time.Date(year, month, day, hour, minute, second, datetime.Nanosecond(), location)
+	}
+	return datetime.In(location), nil
+}
+
+// validateDatetimeSettings checks that the time zones of the prompt file are valid, that time zones are only set for
+// datetime parameters, and that datetime defaults given as strings can be parsed.
+func validateDatetimeSettings(promptFile *PromptFile) error {
+	input := promptFile.Config.Input
+
+	datetimeParameters := make([]string, 0)
+	for key, paramType := range input.Parameters {
+		if strings.EqualFold(paramType, "datetime") {
+			datetimeParameters = append(datetimeParameters, strings.TrimSuffix(key, "?"))
+		}
+	}
+	slices.Sort(datetimeParameters)
+
+	if input.TimeZone != "" {
+		if _, err := time.LoadLocation(input.TimeZone); err != nil {
+			return &PromptError{
+				Message: fmt.Sprintf("invalid time zone: %s", input.TimeZone),
+			}
+		}
+	}
+
+	for _, parameter := range slices.Sorted(maps.Keys(input.TimeZones)) {
+		name := input.TimeZones[parameter]
+		if !slices.Contains(datetimeParameters, parameter) {
+			return &PromptError{
+				Message: fmt.Sprintf("time zone provided for parameter %s which is not a datetime parameter", parameter),
+			}
+		}
+
+		if _, err := time.LoadLocation(name); err != nil {
+			return &PromptError{
+				Message: fmt.Sprintf("invalid time zone for parameter %s: %s", parameter, name),
+			}
+		}
+	}
+
+	for _, parameter := range datetimeParameters {
+		if value, ok := input.Default[parameter].(string); ok {
+			if _, err := promptFile.parseDatetimeParameter(parameter, value); err != nil {
+				return &PromptError{
+					Message: fmt.Sprintf("invalid default value for parameter %s: %s", parameter, value),
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package dotprompt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPromptFile_GetUserPrompt_WithDatetimeParameters(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/datetime.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		values   map[string]interface{}
+		expected string
+	}{
+		{
+			"rfc3339",
+			map[string]interface{}{"meeting": "2024-01-15T14:00:00Z"},
+			"The meeting is at 2024-01-15 09:00 -0500 and the deadline is 2024-03-01 17:00 +0000",
+		},
+		{
+			"layout",
+			map[string]interface{}{"meeting": "15/01/2024", "deadline": "2024-07-01 09:30"},
+			"The meeting is at 2024-01-15 00:00 -0500 and the deadline is 2024-07-01 09:30 +0100",
+		},
+		{
+			"time",
+			map[string]interface{}{"meeting": time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+			"The meeting is at 2024-06-01 08:00 -0400 and the deadline is 2024-03-01 17:00 +0000",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			prompt, err := promptFile.GetUserPrompt(test.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if prompt != test.expected {
+				t.Errorf("Expected prompt '%s', got '%s'", test.expected, prompt)
+			}
+		})
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithInvalidDatetimeString_ReturnsError(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/datetime.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = promptFile.GetUserPrompt(map[string]interface{}{"meeting": "next Tuesday"})
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expected := "parameter meeting is not a datetime"
+	if promptError.Error() != expected {
+		t.Errorf("Expected error '%s', got '%s'", expected, promptError.Error())
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithoutTimeZone_KeepsTimeZone(t *testing.T) {
+	data := "config:\n  input:\n    parameters:\n      when: datetime\nprompts:\n  user: '{{ when | date: \"%H:%M %z\" }}'\n"
+	promptFile, err := NewPromptFile("no-time-zone", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2024, 1, 15, 14, 0, 0, 0, time.FixedZone("", 2*60*60))
+	prompt, err := promptFile.GetUserPrompt(map[string]interface{}{"when": when})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prompt != "14:00 +0200" {
+		t.Errorf("Expected prompt '14:00 +0200', got '%s'", prompt)
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithDateFilterOnString_UsesTimeZone(t *testing.T) {
+	data := "config:\n  input:\n    timeZone: Europe/London\nprompts:\n  user: '{{ \"2024-06-01T12:00:00Z\" | date: \"%H:%M\" }} {{ \"2024-06-01 12:00\" | date: \"%H:%M %z\" }}'\n"
+	promptFile, err := NewPromptFile("date-filter", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := promptFile.GetUserPrompt(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prompt != "13:00 12:00 +0100" {
+		t.Errorf("Expected prompt '13:00 12:00 +0100', got '%s'", prompt)
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithDateFilter_MatchesLiquid(t *testing.T) {
+	data := "config:\n  input:\n    parameters:\n      when?: datetime\n      count?: number\nprompts:\n  user: '{{ when | date: \"%Y\" }}{% if count %}{{ count | date: \"%Y\" }}{% endif %}'\n"
+	promptFile, err := NewPromptFile("date-filter", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := promptFile.GetUserPrompt(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prompt != "0001" {
+		t.Errorf("Expected prompt '0001', got '%s'", prompt)
+	}
+
+	if _, err = promptFile.GetUserPrompt(map[string]interface{}{"count": 42}); err == nil {
+		t.Error("Expected error formatting a number as a date, got none")
+	}
+}
+
+func TestNewPromptFile_WithInvalidDatetimeSettings_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedError string
+	}{
+		{"time-zone", "timeZone: Mars/Olympus", "invalid time zone: Mars/Olympus"},
+		{"parameter-time-zone", "timeZones:\n      when: Mars/Olympus", "invalid time zone for parameter when: Mars/Olympus"},
+		{"not-datetime", "timeZones:\n      topic: UTC", "time zone provided for parameter topic which is not a datetime parameter"},
+		{"default", "default:\n      when: tomorrow", "invalid default value for parameter when: tomorrow"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			data := "config:\n  input:\n    parameters:\n      when: datetime\n      topic: string\n    " + test.input + "\n" + minimalPrompt
+			_, err := NewPromptFile(test.name, []byte(data))
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
@@ -1,20 +1,19 @@
 package dotprompt
 
 import (
-	"bytes"
 	"fmt"
 	"gopkg.in/osteele/liquid.v1"
 	"gopkg.in/yaml.v3"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
-	"time"
 )
 
 var (
-	validDataTypes      = []string{"string", "number", "bool", "datetime", "object"}
+	validDataTypes      = []string{"string", "number", "bool", "datetime", "object", "image", "file"}
 	invalidCharsRegex   = regexp.MustCompile(`([^A-Za-z0-9 \-\r\n]*)`)
 	multipleSpacesRegex = regexp.MustCompile(`[\s\r\n]+`)
 )
@@ -22,10 +21,15 @@ var (
 // PromptError represents an error related to prompt processing.
 type PromptError struct {
 	Message string
+	Source  *SourceMetadata
 }
 
-// Error returns the error message associated with the PromptError.
+// Error returns the error message associated with the PromptError, followed by the path of the prompt file if the
+// error occurred while loading it.
 func (e PromptError) Error() string {
+	if e.Source != nil && e.Source.Path != "" {
+		return fmt.Sprintf("%s (source: %s)", e.Message, e.Source.Path)
+	}
 	return e.Message
 }
 
@@ -46,6 +50,22 @@ func (of *OutputFormat) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// UnmarshalText unmarshals the text of a JSON or TOML value into an OutputFormat value, supporting "text" and "json".
+// Returns an error if format is invalid.
+func (of *OutputFormat) UnmarshalText(text []byte) error {
+	return of.UnmarshalYAML(&yaml.Node{Value: string(text)})
+}
+
+// MarshalText marshals the OutputFormat into the text used for JSON and TOML values.
+// Returns an error if the format is invalid.
+func (of OutputFormat) MarshalText() ([]byte, error) {
+	value, err := of.MarshalYAML()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value.(string)), nil
+}
+
 // MarshalYAML marshals the OutputFormat into a YAML-compatible representation.
 // Returns a string representation of the format ("text" or "json") or an error if the format is invalid.
 func (of OutputFormat) MarshalYAML() (interface{}, error) {
@@ -81,68 +101,167 @@ func (of *OutputFormat) String() string {
 
 // PromptFile represents the structure of a file containing a prompt configuration and multiple associated prompts.
 type PromptFile struct {
-	Name     string              `yaml:"name,omitempty"`
-	Model    string              `yaml:"model,omitempty"`
-	Config   PromptConfig        `yaml:"config"`
-	Prompts  Prompts             `yaml:"prompts"`
-	FewShots []FewShotPromptPair `yaml:"fewShots,omitempty"`
+	Name    string       `yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`
+	Model   string       `yaml:"model,omitempty" json:"model,omitempty" toml:"model,omitempty"`
+	Config  PromptConfig `yaml:"config" json:"config" toml:"config"`
+	Prompts Prompts      `yaml:"prompts,omitempty" json:"prompts,omitempty" toml:"prompts,omitempty"`
+
+	// Messages holds role-tagged messages, used in place of the system and user pair in Prompts
+	Messages []MessageTemplate   `yaml:"messages,omitempty" json:"messages,omitempty" toml:"messages,omitempty"`
+	FewShots []FewShotPromptPair `yaml:"fewShots,omitempty" json:"fewShots,omitempty" toml:"fewShots,omitempty"`
+	Tools    []Tool              `yaml:"tools,omitempty" json:"tools,omitempty" toml:"tools,omitempty"`
+
+	// Overrides holds the model and configuration values used in place of those of the prompt file when a profile is
+	// active, keyed by the name of the profile
+	Overrides map[string]ProfileOverride `yaml:"overrides,omitempty" json:"overrides,omitempty" toml:"overrides,omitempty"`
+
+	// Namespace holds the directory the prompt file was loaded from when loaded using WithNamespaces. It qualifies the
+	// Name, but is not written when the prompt file is serialized
+	Namespace string `yaml:"-" json:"-" toml:"-"`
+
+	// Source describes where the prompt file was loaded from
+	Source SourceMetadata `yaml:"-" json:"-" toml:"-"`
+
+	// Revision holds the commit hash of the prompt file when it was loaded from a git repository
+	Revision string `yaml:"-" json:"-" toml:"-"`
+
+	// Format is the layout used when the prompt file is serialized
+	Format FileFormat `yaml:"-" json:"-" toml:"-"`
+
+	// document and baseline hold the original YAML document and the prompt file as it was loaded, when created with
+	// WithEditMode. The nodes are shared by every copy of the prompt file, so they are never modified after loading
+	// and serializeDocument merges changes into a clone
+	document *yaml.Node
+	baseline *yaml.Node
+
+	// resolved is set on the copies returned with the overrides of a profile or a model alias resolved, which cannot
+	// be serialized without writing the resolved values in place of those of the prompt file
+	resolved bool
+
+	// coerce converts parameter values to their declared type when set using WithCoercion or WithParameterCoercion,
+	// in addition to when the prompt file sets Coerce
+	coerce bool
+
+	// mediaRoot is the directory image and file parameters can be read from, when set using WithMediaRoot
+	mediaRoot string
 }
 
-// PromptConfig represents the configuration options for a prompt, including temperature, max tokens, output
-// format, and input schema.
+// PromptConfig represents the configuration options for a prompt, including the model parameters, output format,
+// response caching, and input schema. Model parameters without a field of their own are held in Extra, which is
+// passed to the model as-is.
 type PromptConfig struct {
-	Temperature  *float32     `yaml:"temperature,omitempty"`
-	MaxTokens    *int         `yaml:"maxTokens,omitempty"`
-	OutputFormat OutputFormat `yaml:"outputFormat"`
-	Input        InputSchema  `yaml:"input"`
+	Temperature      *float32               `yaml:"temperature,omitempty" json:"temperature,omitempty" toml:"temperature,omitempty"`
+	MaxTokens        *int                   `yaml:"maxTokens,omitempty" json:"maxTokens,omitempty" toml:"maxTokens,omitempty"`
+	TopP             *float32               `yaml:"topP,omitempty" json:"topP,omitempty" toml:"topP,omitempty"`
+	TopK             *int                   `yaml:"topK,omitempty" json:"topK,omitempty" toml:"topK,omitempty"`
+	Stop             []string               `yaml:"stop,omitempty" json:"stop,omitempty" toml:"stop,omitempty"`
+	Seed             *int                   `yaml:"seed,omitempty" json:"seed,omitempty" toml:"seed,omitempty"`
+	PresencePenalty  *float32               `yaml:"presencePenalty,omitempty" json:"presencePenalty,omitempty" toml:"presencePenalty,omitempty"`
+	FrequencyPenalty *float32               `yaml:"frequencyPenalty,omitempty" json:"frequencyPenalty,omitempty" toml:"frequencyPenalty,omitempty"`
+	ReasoningEffort  string                 `yaml:"reasoningEffort,omitempty" json:"reasoningEffort,omitempty" toml:"reasoningEffort,omitempty"`
+	Extra            map[string]interface{} `yaml:"extra,omitempty" json:"extra,omitempty" toml:"extra,omitempty"`
+	OutputFormat     OutputFormat           `yaml:"outputFormat" json:"outputFormat" toml:"outputFormat"`
+	Cache            *bool                  `yaml:"cache,omitempty" json:"cache,omitempty" toml:"cache,omitempty"`
+	Input            InputSchema            `yaml:"input" json:"input" toml:"input"`
 }
 
 // InputSchema represents the schema for input parameters and their default values.
 type InputSchema struct {
-	Parameters map[string]string      `yaml:"parameters"`
-	Default    map[string]interface{} `yaml:"default,omitempty"`
+	Parameters map[string]string      `yaml:"parameters" json:"parameters" toml:"parameters"`
+	Default    map[string]interface{} `yaml:"default,omitempty" json:"default,omitempty" toml:"default,omitempty"`
+
+	// MaxMediaSize is the maximum size, in bytes, of any image or file parameter, which defaults to DefaultMaxMediaSize
+	MaxMediaSize int `yaml:"maxMediaSize,omitempty" json:"maxMediaSize,omitempty" toml:"maxMediaSize,omitempty"`
+
+	// DateLayouts holds the layouts, in addition to RFC 3339, accepted for datetime parameters given as strings
+	DateLayouts []string `yaml:"dateLayouts,omitempty" json:"dateLayouts,omitempty" toml:"dateLayouts,omitempty"`
+
+	// TimeZone is the time zone datetime parameters are rendered in, and TimeZones holds the time zone for individual
+	// parameters in place of it
+	TimeZone  string            `yaml:"timeZone,omitempty" json:"timeZone,omitempty" toml:"timeZone,omitempty"`
+	TimeZones map[string]string `yaml:"timeZones,omitempty" json:"timeZones,omitempty" toml:"timeZones,omitempty"`
+
+	// Coerce converts string, number, and bool parameters with a compatible type, such as the string "42" for a
+	// number, to the declared type. Unsigned integers too large for an int64 become a float64, losing precision, and
+	// only scalar values are converted to strings
+	Coerce bool `yaml:"coerce,omitempty" json:"coerce,omitempty" toml:"coerce,omitempty"`
 }
 
 // Prompts represents a set of system and user prompts.
 type Prompts struct {
-	System string `yaml:"system,omitempty"`
-	User   string `yaml:"user"`
+	System string `yaml:"system,omitempty" json:"system,omitempty" toml:"system,omitempty"`
+	User   string `yaml:"user" json:"user" toml:"user"`
 }
 
 // FewShotPromptPair represents a pair of user prompt and the corresponding response.
 type FewShotPromptPair struct {
-	User     string `yaml:"user"`
-	Response string `yaml:"response"`
+	User     string `yaml:"user" json:"user" toml:"user"`
+	Response string `yaml:"response" json:"response" toml:"response"`
 }
 
 // NewPromptFileFromFile reads a file from the specified path, processes its content, and returns a PromptFile
 // structure or an error.
-func NewPromptFileFromFile(path string) (*PromptFile, error) {
+func NewPromptFileFromFile(path string, options ...PromptFileOption) (*PromptFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	source := SourceMetadata{Path: path, Loader: FileSource}
+	if info, statErr := os.Stat(path); statErr == nil {
+		source.ModTime = info.ModTime()
+	}
+
+	return newPromptFileFromSource(promptFileNameFromPath(path), data, source, options...)
+}
+
+// promptFileNameFromPath returns the lower-cased base name of a prompt file path with its extension removed, which is
+// used as the prompt file name when one is not specified in the file.
+func promptFileNameFromPath(path string) string {
 	fileName := strings.ToLower(filepath.Base(path))
 	extension := filepath.Ext(fileName)
-	promptFileName := strings.TrimSuffix(fileName, extension)
-
-	return NewPromptFile(promptFileName, data)
+	if format, ok := promptFileFormatFromPath(fileName); ok {
+		extension = format.extension()
+	}
+	return strings.TrimSuffix(fileName, extension)
 }
 
 // NewPromptFile creates a new PromptFile from the provided name and prompt data.
-// It validates the input, configures the prompt file, and returns an error if any issues are encountered.
-func NewPromptFile(name string, data []byte) (*PromptFile, error) {
-	promptFile := &PromptFile{}
-	err := yaml.Unmarshal(data, promptFile)
+// It validates the input, configures the prompt file, and returns an error if any issues are encountered. Prompt data
+// in the Genkit front matter format is detected automatically.
+func NewPromptFile(name string, data []byte, options ...PromptFileOption) (*PromptFile, error) {
+	return NewPromptFileWithFormat(name, data, NativeFormat, options...)
+}
+
+// NewPromptFileWithFormat creates a new PromptFile from the provided name and prompt data in the specified format, in
+// the same way as NewPromptFile. Prompt data in the native and front matter formats is detected automatically when
+// either of those formats is specified.
+func NewPromptFileWithFormat(name string, data []byte, format FileFormat, options ...PromptFileOption) (*PromptFile, error) {
+	promptFile, err := decodePromptFile(data, format, newPromptFileOptions(options))
 	if err != nil {
-		return nil, &PromptError{
-			Message: fmt.Sprintf("failed to parse prompt file: %v", err),
-		}
+		return nil, err
 	}
 
-	if len(promptFile.Prompts.User) == 0 {
-		return nil, &PromptError{
+	if err = validatePromptFile(promptFile, name); err != nil {
+		return nil, err
+	}
+
+	if err = promptFile.captureBaseline(); err != nil {
+		return nil, err
+	}
+
+	return promptFile, nil
+}
+
+// validatePromptFile validates the prompts, parameters, and tools of the prompt file, and sets its name to the cleaned
+// form of the name in the prompt file, or of the provided name if the prompt file does not specify one.
+func validatePromptFile(promptFile *PromptFile, name string) error {
+	if len(promptFile.Messages) > 0 {
+		if err := validateMessages(promptFile); err != nil {
+			return err
+		}
+	} else if len(promptFile.Prompts.User) == 0 {
+		return &PromptError{
 			Message: "no user prompt template was provided in the prompt file",
 		}
 	}
@@ -154,58 +273,86 @@ func NewPromptFile(name string, data []byte) (*PromptFile, error) {
 	promptFile.Name = cleanName(promptFile.Name)
 
 	if len(promptFile.Name) == 0 {
-		return nil, &PromptError{
+		return &PromptError{
 			Message: "the prompt file name, once cleaned, is empty",
 		}
 	}
 
 	for key, paramType := range promptFile.Config.Input.Parameters {
 		if !slices.Contains(validDataTypes, paramType) {
-			return nil, &PromptError{
+			return &PromptError{
 				Message: fmt.Sprintf("invalid data type for parameter %s: %s", key, paramType),
 			}
 		}
 	}
 
-	return promptFile, nil
+	if err := validateModelParameters(promptFile.Config); err != nil {
+		return err
+	}
+
+	if err := validateDatetimeSettings(promptFile); err != nil {
+		return err
+	}
+
+	if err := validateOverrides(promptFile); err != nil {
+		return err
+	}
+
+	return validateTools(promptFile.Tools)
 }
 
 // GetSystemPrompt generates the system prompt string using the provided template values, appending
-// JSON format instructions if required.
+// JSON format instructions if required. For prompt files using the `messages` form this is the first system message.
 func (pf *PromptFile) GetSystemPrompt(values map[string]interface{}) (string, error) {
-	systemPrompt := pf.Prompts.System
-	if pf.Config.OutputFormat == Json &&
-		!strings.Contains(strings.ToLower(systemPrompt), "json") &&
-		!strings.Contains(strings.ToLower(pf.Prompts.User), "json") {
-
-		promptSuffix := "Please provide the response in JSON"
-
-		if len(systemPrompt) == 0 {
-			systemPrompt = promptSuffix
-		} else {
-			systemPrompt += " " + promptSuffix
-		}
+	systemPrompt := pf.systemTemplate()
+	if pf.requiresJsonInstruction() {
+		systemPrompt = appendJsonInstruction(systemPrompt)
 	}
 
 	return pf.generatePrompt(systemPrompt, values)
 }
 
 // GetUserPrompt generates a user prompt string based on a provided template and a set of values.
-// It utilizes the 'Prompts.User' template within the PromptFile and replaces template placeholders with
-// corresponding values from the input map.
+// It utilizes the 'Prompts.User' template within the PromptFile, or the last user message for prompt files using
+// the `messages` form, and replaces template placeholders with corresponding values from the input map.
 func (pf *PromptFile) GetUserPrompt(values map[string]interface{}) (string, error) {
-	return pf.generatePrompt(pf.Prompts.User, values)
+	return pf.generatePrompt(pf.userTemplate(), values)
 }
 
 // generatePrompt generates a prompt by rendering a given template with provided values, utilizing the liquid
-// templating engine. Returns the rendered prompt string or an error in case of failure.
+// templating engine. Returns the rendered prompt string or an error in case of failure. Image and file parameters
+// are omitted from the text, use GetUserContent to include them.
 func (pf *PromptFile) generatePrompt(template string, values map[string]interface{}) (string, error) {
-	engine := liquid.NewEngine()
-	bindings, err := pf.parseAndValidateParameters(values)
+	prompt, _, err := pf.renderTemplate(template, values)
 	if err != nil {
 		return "", err
 	}
 
+	return removeMediaMarkers(prompt), nil
+}
+
+// renderTemplate renders a template with the provided values using the liquid templating engine. Image and file
+// parameters are rendered as placeholders, and the media they refer to is returned keyed by parameter name.
+func (pf *PromptFile) renderTemplate(template string, values map[string]interface{}) (string, map[string]ContentPart, error) {
+	bindings, media, err := pf.parseAndValidateParameters(values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	prompt, err := pf.renderLiquid(template, bindings)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return prompt, media, nil
+}
+
+// renderLiquid renders a template with a set of validated bindings using the liquid templating engine, with the date
+// filter replaced by one which honours the time zone of the prompt file.
+func (pf *PromptFile) renderLiquid(template string, bindings map[string]interface{}) (string, error) {
+	engine := liquid.NewEngine()
+	engine.RegisterFilter("date", pf.dateFilter)
+
 	prompt, err := engine.ParseAndRenderString(template, bindings)
 	if err != nil {
 		return "", &PromptError{
@@ -217,8 +364,11 @@ func (pf *PromptFile) generatePrompt(template string, values map[string]interfac
 }
 
 // parseAndValidateParameters parses input parameters against the configuration, providing default values and validation.
-func (pf *PromptFile) parseAndValidateParameters(values map[string]interface{}) (map[string]interface{}, error) {
+// Image and file parameters are loaded and returned separately, with their bindings replaced by placeholders.
+func (pf *PromptFile) parseAndValidateParameters(values map[string]interface{}) (map[string]interface{}, map[string]ContentPart, error) {
 	bindings := make(map[string]interface{})
+	media := make(map[string]ContentPart)
+	parameterTypes := make(map[string]string)
 
 	if values == nil {
 		values = make(map[string]interface{})
@@ -229,6 +379,7 @@ func (pf *PromptFile) parseAndValidateParameters(values map[string]interface{})
 		// Get the current key without the `optional` suffix, then get the parameters type
 		keyWithoutOptionalSuffix := strings.TrimSuffix(key, "?")
 		parameterType := strings.ToLower(pf.Config.Input.Parameters[key])
+		parameterTypes[keyWithoutOptionalSuffix] = parameterType
 		if value, ok := values[keyWithoutOptionalSuffix]; ok {
 			// If the parameter value is an object which implements the fmt.Stringer interface then use this
 			// to convert the object to its string representation. Otherwise, generate a string version of the
@@ -247,55 +398,81 @@ func (pf *PromptFile) parseAndValidateParameters(values map[string]interface{})
 			bindings[keyWithoutOptionalSuffix] = defaultValue
 		} else if !strings.HasSuffix(key, "?") {
 			// User has not provided a value for a required parameter
-			return nil, &PromptError{
+			return nil, nil, &PromptError{
 				Message: fmt.Sprintf("no value provided for parameter %s", key),
 			}
 		}
 	}
 
 	// Iterate over all the set values and make sure that their types conform to the prompt file defined
-	// types
+	// types, converting them first when coercion is enabled
 	for key, value := range bindings {
-		expectedType := pf.Config.Input.Parameters[key]
+		expectedType := parameterTypes[key]
+		if pf.Config.Input.Coerce || pf.coerce {
+			coerced, err := coerceParameter(key, expectedType, value)
+			if err != nil {
+				return nil, nil, err
+			}
+			value = coerced
+			bindings[key] = value
+		}
+
 		switch expectedType {
 		case "string":
 			if _, ok := value.(string); !ok {
-				return nil, &PromptError{
+				return nil, nil, &PromptError{
 					Message: fmt.Sprintf("parameter %s is not a string", key),
 				}
 			}
 			break
 		case "number":
 			if !isNumeric(value) {
-				return nil, &PromptError{
+				return nil, nil, &PromptError{
 					Message: fmt.Sprintf("parameter %s is not a number", key),
 				}
 			}
+
+			number, ok := signedNumber(value)
+			if !ok {
+				return nil, nil, &PromptError{
+					Message: fmt.Sprintf("parameter %s is out of range", key),
+				}
+			}
+			bindings[key] = number
 			break
 		case "bool":
 			if _, ok := value.(bool); !ok {
-				return nil, &PromptError{
+				return nil, nil, &PromptError{
 					Message: fmt.Sprintf("parameter %s is not a bool", key),
 				}
 			}
 			break
 		case "datetime":
-			if _, ok := value.(time.Time); !ok {
-				return nil, &PromptError{
-					Message: fmt.Sprintf("parameter %s is not a datetime", key),
-				}
+			datetime, err := pf.parseDatetimeParameter(key, value)
+			if err != nil {
+				return nil, nil, err
 			}
+			bindings[key] = datetime
 			break
+		case "image", "file":
+			part, err := pf.loadMedia(key, expectedType, value)
+			if err != nil {
+				return nil, nil, err
+			}
+			media[key] = part
+			bindings[key] = mediaMarker(key)
 		}
 	}
 
-	return bindings, nil
+	return bindings, media, nil
 }
 
-// ToFile serializes the PromptFile and writes it to a specified file.
-// Returns an error if the serialization or file write operation fails.
+// ToFile serializes the PromptFile and writes it to a specified file. Files with the .prompt.json or .prompt.toml
+// extensions are written in the JSON or TOML format respectively.
+// Returns an error if the serialization or file write operation fails, or if the prompt file was returned by
+// ResolveProfile or GetPromptFile with a profile or model alias resolved.
 func (pf *PromptFile) ToFile(name string) error {
-	content, err := pf.Serialize()
+	content, err := encodePromptFile(pf, pf.serializationFormatForPath(name))
 	if err != nil {
 		return err
 	}
@@ -311,21 +488,11 @@ func (pf *PromptFile) ToFile(name string) error {
 }
 
 // Serialize serializes the PromptFile into a byte slice in YAML format and returns it, or an error if serialization
-// fails.
+// fails. Prompt files with a Format of FrontMatterFormat, JSONFormat, or TOMLFormat are serialized in that format
+// instead, and prompt files created using WithEditMode are serialized by updating their original YAML document. Prompt
+// files with a profile or model alias resolved cannot be serialized, so serialize those in Manager.PromptFiles instead.
 func (pf *PromptFile) Serialize() ([]byte, error) {
-	var b bytes.Buffer
-
-	encoder := yaml.NewEncoder(&b)
-	encoder.SetIndent(2)
-
-	err := encoder.Encode(&pf)
-	if err != nil {
-		return nil, &PromptError{
-			Message: fmt.Sprintf("failed to marshal prompt file: %v", err),
-		}
-	}
-
-	return b.Bytes(), nil
+	return encodePromptFile(pf, pf.Format)
 }
 
 // cleanName sanitizes the provided name string by removing invalid characters, replacing multiple spaces with a hyphen,
@@ -342,10 +509,35 @@ func isNumeric(value interface{}) bool {
 	switch value.(type) {
 	case int, int8, int16, int32, int64:
 		return true
-	//case uint, uint8, uint16, uint32, uint64:
-	//	return true
+	case uint, uint8, uint16, uint32, uint64:
+		return true
 	case float32, float64:
 		return true
 	}
 	return false
 }
+
+// signedNumber converts unsigned integers to an int64, which the template engine is able to compare with other
+// numbers, and returns other values unchanged. Returns false if the unsigned integer is too large for an int64.
+func signedNumber(value interface{}) (interface{}, bool) {
+	var unsigned uint64
+	switch typedValue := value.(type) {
+	case uint:
+		unsigned = uint64(typedValue)
+	case uint8:
+		unsigned = uint64(typedValue)
+	case uint16:
+		unsigned = uint64(typedValue)
+	case uint32:
+		unsigned = uint64(typedValue)
+	case uint64:
+		unsigned = typedValue
+	default:
+		return value, true
+	}
+
+	if unsigned > math.MaxInt64 {
+		return nil, false
+	}
+	return int64(unsigned), true
+}
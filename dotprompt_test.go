@@ -427,6 +427,67 @@ func TestPromptFile_GetUserPrompt_WithAllParameterTypes(t *testing.T) {
 	}
 }
 
+func TestPromptFile_GetUserPrompt_WithOptionalParameters_ChecksTypes(t *testing.T) {
+	data := "config:\n  input:\n    parameters:\n      name?: string\n      count?: number\nprompts:\n  user: '{{ name }} {{ count }}'\n"
+	promptFile, err := NewPromptFile("optional-types", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		parameters    map[string]interface{}
+		expectedError string
+	}{
+		{"absent", map[string]interface{}{}, ""},
+		{"valid", map[string]interface{}{"name": "Arthur", "count": 42}, ""},
+		{"invalid-string", map[string]interface{}{"name": 42}, "parameter name is not a string"},
+		{"invalid-number", map[string]interface{}{"count": "42"}, "parameter count is not a number"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := promptFile.GetUserPrompt(test.parameters)
+
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithMismatchedDefault_ReturnsError(t *testing.T) {
+	data := "config:\n  input:\n    parameters:\n      name?: string\n    default:\n      name: 42\nprompts:\n  user: '{{ name }}'\n"
+	promptFile, err := NewPromptFile("mismatched-default", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = promptFile.GetUserPrompt(nil)
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	if promptError.Error() != "parameter name is not a string" {
+		t.Errorf("Expected error 'parameter name is not a string', got '%s'", promptError.Error())
+	}
+}
+
 func TestPromptFile_GetUserPrompt_WithInvalidParameterValues_ReturnsError(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -519,11 +580,11 @@ func TestPromptFile_GetUserPrompt_WithNumericValues_ReturnsCorrectPrompt(t *test
 		{"int16", int16(8), "Pass"},
 		{"int32", int32(8), "Pass"},
 		{"int64", int64(8), "Pass"},
-		//{"uint", uint(8), "Pass"},
-		//{"uint8", uint8(8), "Pass"},
-		//{"uint16", uint16(8), "Pass"},
-		//{"uint32", uint32(8), "Pass"},
-		//{"uint64", uint64(8), "Pass"},
+		{"uint", uint(8), "Pass"},
+		{"uint8", uint8(8), "Pass"},
+		{"uint16", uint16(8), "Pass"},
+		{"uint32", uint32(8), "Pass"},
+		{"uint64", uint64(8), "Pass"},
 		{"float32", float32(8), "Pass"},
 		{"float64", float64(8), "Pass"},
 		{"low-value", 2, ""},
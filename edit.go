@@ -0,0 +1,209 @@
+package dotprompt
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeDocument parses the YAML prompt data into the prompt file, retaining the parsed document so that it can be
+// updated when the prompt file is serialized.
+func (pf *PromptFile) decodeDocument(data []byte) error {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	if err := document.Decode(pf); err != nil {
+		return err
+	}
+
+	if document.Kind == yaml.DocumentNode && len(document.Content) > 0 && document.Content[0].Kind == yaml.MappingNode {
+		pf.document = &document
+	}
+
+	return nil
+}
+
+// captureBaseline records the encoded form of the prompt file as it was loaded, which is compared with the prompt
+// file when it is serialized to find the values which have changed.
+func (pf *PromptFile) captureBaseline() error {
+	if pf.document == nil {
+		return nil
+	}
+
+	baseline := &yaml.Node{}
+	if err := baseline.Encode(pf); err != nil {
+		return &PromptError{
+			Message: fmt.Sprintf("failed to parse prompt file: %v", err),
+		}
+	}
+	pf.baseline = baseline
+
+	return nil
+}
+
+// serializeDocument serializes the prompt file by applying the values which have changed since it was loaded to a
+// copy of the original document.
+func (pf *PromptFile) serializeDocument() ([]byte, error) {
+	current := &yaml.Node{}
+	if err := current.Encode(pf); err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("failed to marshal prompt file: %v", err),
+		}
+	}
+
+	document := cloneNode(pf.document)
+	document.Content[0] = mergeNode(document.Content[0], pf.baseline, current)
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(document); err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("failed to marshal prompt file: %v", err),
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// mergeNode applies the differences between the baseline and current nodes to the original node, returning the
+// updated node. The original node is returned unchanged when the baseline and current nodes are the same, so that its
+// comments and style are kept.
+func mergeNode(original *yaml.Node, baseline *yaml.Node, current *yaml.Node) *yaml.Node {
+	if nodesEqual(baseline, current) {
+		return original
+	}
+
+	if original.Kind != current.Kind || baseline.Kind != current.Kind {
+		return replaceNode(original, current)
+	}
+
+	switch current.Kind {
+	case yaml.MappingNode:
+		mergeMapping(original, baseline, current)
+	case yaml.SequenceNode:
+		mergeSequence(original, baseline, current)
+	default:
+		if original.Tag != current.Tag {
+			original.Style = current.Style
+		}
+		original.Tag = current.Tag
+		original.Value = current.Value
+	}
+
+	return original
+}
+
+// mergeMapping applies the changes to the keys of a mapping to the original mapping. Changed values are updated in
+// place, removed keys are deleted, and new keys are added to the end of the mapping.
+func mergeMapping(original *yaml.Node, baseline *yaml.Node, current *yaml.Node) {
+	for i := 0; i+1 < len(baseline.Content); i += 2 {
+		key := baseline.Content[i].Value
+		if mappingValue(current, key) == nil {
+			removeMappingKey(original, key)
+		}
+	}
+
+	for i := 0; i+1 < len(current.Content); i += 2 {
+		key, value := current.Content[i], current.Content[i+1]
+		baselineValue := mappingValue(baseline, key.Value)
+
+		originalIndex := mappingIndex(original, key.Value)
+		if originalIndex >= 0 && baselineValue != nil {
+			original.Content[originalIndex+1] = mergeNode(original.Content[originalIndex+1], baselineValue, value)
+			continue
+		}
+
+		// Keys which were not in the original document, such as defaults added when the prompt file was encoded, are
+		// only added once their value differs from the value when the prompt file was loaded
+		if baselineValue != nil && nodesEqual(baselineValue, value) {
+			continue
+		}
+
+		if originalIndex >= 0 {
+			original.Content[originalIndex+1] = replaceNode(original.Content[originalIndex+1], value)
+			continue
+		}
+
+		original.Content = append(original.Content, cloneNode(key), cloneNode(value))
+	}
+}
+
+// mergeSequence applies the changes to the items of a sequence to the original sequence, matching items by their
+// position. Items beyond the end of the current sequence are removed, and new items are added to the end.
+func mergeSequence(original *yaml.Node, baseline *yaml.Node, current *yaml.Node) {
+	content := make([]*yaml.Node, 0, len(current.Content))
+	for i, value := range current.Content {
+		if i < len(original.Content) && i < len(baseline.Content) {
+			content = append(content, mergeNode(original.Content[i], baseline.Content[i], value))
+		} else {
+			content = append(content, cloneNode(value))
+		}
+	}
+	original.Content = content
+}
+
+// replaceNode returns a copy of the replacement node carrying the comments of the original node.
+func replaceNode(original *yaml.Node, replacement *yaml.Node) *yaml.Node {
+	node := cloneNode(replacement)
+	node.HeadComment = original.HeadComment
+	node.LineComment = original.LineComment
+	node.FootComment = original.FootComment
+	return node
+}
+
+// mappingIndex returns the index of the key within the content of the mapping node, or -1 if it is not found.
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingValue returns the value of the key within the mapping node, or nil if it is not found.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if index := mappingIndex(mapping, key); index >= 0 {
+		return mapping.Content[index+1]
+	}
+	return nil
+}
+
+// removeMappingKey removes the key and its value from the mapping node.
+func removeMappingKey(mapping *yaml.Node, key string) {
+	if index := mappingIndex(mapping, key); index >= 0 {
+		mapping.Content = append(mapping.Content[:index], mapping.Content[index+2:]...)
+	}
+}
+
+// nodesEqual returns true if the nodes hold the same values, ignoring their style, comments, and position.
+func nodesEqual(a *yaml.Node, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Tag != b.Tag || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+
+	for i := range a.Content {
+		if !nodesEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cloneNode returns a deep copy of the node.
+func cloneNode(node *yaml.Node) *yaml.Node {
+	clone := *node
+	if node.Content != nil {
+		clone.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			clone.Content[i] = cloneNode(child)
+		}
+	}
+	return &clone
+}
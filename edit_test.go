@@ -0,0 +1,215 @@
+package dotprompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPromptFile_Serialize_WithEditMode_Unchanged(t *testing.T) {
+	original, err := os.ReadFile("test-data/commented.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFile, err := NewPromptFile("commented", original, WithEditMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialized, err := promptFile.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(serialized) != string(original) {
+		t.Errorf("Expected serialized prompt file to be '%s', got '%s'", original, serialized)
+	}
+}
+
+func TestPromptFile_Serialize_WithEditMode_AppliesChanges(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/commented.prompt", WithEditMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	temperature := float32(0.2)
+	maxTokens := 100
+	promptFile.Config.Temperature = &temperature
+	promptFile.Config.MaxTokens = &maxTokens
+	promptFile.Prompts.System = "You are a helpful research assistant.\nKeep your answers very short."
+	promptFile.FewShots = append(promptFile.FewShots, FewShotPromptPair{
+		User:     "What is Wi-Fi?",
+		Response: "A wireless networking technology.",
+	})
+
+	expected := `# Prompt used by the research assistant
+model: gpt-4o
+config:
+  temperature: 0.2 # tuned for descriptive answers
+  outputFormat: text
+  input:
+    parameters:
+      topic: string
+  maxTokens: 100
+prompts:
+  # The system prompt is shared with the summariser
+  system: |-
+    You are a helpful research assistant.
+    Keep your answers very short.
+  user: |-
+    Explain the impact of {{ topic }}
+    on society
+fewShots:
+  - user: What is Bluetooth?
+    response: A short-range wireless technology.
+  - user: What is Wi-Fi?
+    response: A wireless networking technology.
+`
+
+	serialized, err := promptFile.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(serialized) != expected {
+		t.Errorf("Expected serialized prompt file to be '%s', got '%s'", expected, serialized)
+	}
+}
+
+func TestPromptFile_Serialize_WithEditMode_EditedCopies(t *testing.T) {
+	data, err := os.ReadFile("test-data/commented.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFSStore(fstest.MapFS{"commented.prompt": {Data: data}}, WithNamespaces(), WithPromptFileOptions(WithEditMode()))
+	manager, err := NewManagerFromLoader(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := manager.GetPromptFile("commented")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := manager.GetPromptFile("commented")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	temperature := float32(0.2)
+	first.Config.Temperature = &temperature
+	second.Prompts.System = "You are a helpful assistant."
+	second.FewShots = append(second.FewShots, FewShotPromptPair{User: "What is Wi-Fi?", Response: "A wireless networking technology."})
+
+	firstSerialized, err := first.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondSerialized, err := second.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Serializing the second copy must not change the document shared with the first
+	firstReserialized, err := first.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(firstReserialized) != string(firstSerialized) {
+		t.Errorf("Expected the first copy to serialize the same again, got '%s' and '%s'", firstSerialized, firstReserialized)
+	}
+
+	if !strings.Contains(string(firstSerialized), "temperature: 0.2 # tuned") || strings.Contains(string(firstSerialized), "Wi-Fi") {
+		t.Errorf("Expected only the first copy's changes, got '%s'", firstSerialized)
+	}
+
+	if !strings.Contains(string(secondSerialized), "temperature: 0.6 # tuned") || !strings.Contains(string(secondSerialized), "Wi-Fi") {
+		t.Errorf("Expected only the second copy's changes, got '%s'", secondSerialized)
+	}
+
+	managed := manager.PromptFiles["commented"]
+	original, err := managed.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(original) != string(data) {
+		t.Errorf("Expected the managed prompt file to be unchanged, got '%s'", original)
+	}
+}
+
+func TestPromptFile_Serialize_WithEditMode_RemovesFields(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/commented.prompt", WithEditMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFile.FewShots = nil
+	promptFile.Config.Temperature = nil
+
+	serialized, err := promptFile.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, removed := range []string{"fewShots:", "temperature:", "Bluetooth"} {
+		if strings.Contains(string(serialized), removed) {
+			t.Errorf("Expected '%s' to be removed, got '%s'", removed, serialized)
+		}
+	}
+
+	if !strings.HasPrefix(string(serialized), "# Prompt used by the research assistant\n") {
+		t.Errorf("Expected the head comment to be kept, got '%s'", serialized)
+	}
+}
+
+func TestPromptFile_ToFile_WithEditMode_RoundTrips(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/commented.prompt", WithEditMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFile.Model = "gpt-4o-mini"
+
+	filePath := filepath.Join(t.TempDir(), "commented.prompt")
+	if err = promptFile.ToFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// A copy of the prompt file keeps the original document, so the first change is not applied twice
+	copied := *promptFile
+	copied.Model = "gpt-4o"
+	serialized, err := copied.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := os.ReadFile("test-data/commented.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(serialized) != string(original) {
+		t.Errorf("Expected reverting the change to produce the original file, got '%s'", serialized)
+	}
+
+	reloaded, err := NewPromptFileFromFile(filePath, WithEditMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reloaded.Model != "gpt-4o-mini" {
+		t.Errorf("Expected model to be 'gpt-4o-mini', got '%s'", reloaded.Model)
+	}
+
+	if reloaded.Prompts != promptFile.Prompts {
+		t.Errorf("Expected prompts %+v, got %+v", promptFile.Prompts, reloaded.Prompts)
+	}
+}
@@ -0,0 +1,181 @@
+package dotprompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileFormat represents the layout of a prompt file on disk.
+type FileFormat int
+
+const (
+
+	// NativeFormat represents a prompt file which is a single YAML document with the templates held in the prompts or
+	// messages sections.
+	NativeFormat FileFormat = iota
+
+	// FrontMatterFormat represents a prompt file in the Genkit Dotprompt layout, which is YAML front matter between
+	// `---` delimiters followed by a template body using `{{role "..."}}` markers to separate messages. The body is
+	// still rendered as a Liquid template, so only simple `{{ variable }}` substitution is portable between the two, and
+	// prompt files using Handlebars block helpers such as `{{#if}}` are rejected when they are loaded.
+	FrontMatterFormat
+
+	// JSONFormat represents a prompt file with the same structure as NativeFormat, written as a JSON document.
+	JSONFormat
+
+	// TOMLFormat represents a prompt file with the same structure as NativeFormat, written as a TOML document.
+	TOMLFormat
+)
+
+const (
+	jsonPromptFileExtension string = promptFileExtension + ".json"
+	tomlPromptFileExtension string = promptFileExtension + ".toml"
+)
+
+// extension returns the file extension used for prompt files in the format.
+func (f FileFormat) extension() string {
+	switch f {
+	case JSONFormat:
+		return jsonPromptFileExtension
+	case TOMLFormat:
+		return tomlPromptFileExtension
+	default:
+		return promptFileExtension
+	}
+}
+
+// promptFileFormatFromPath returns the format of a prompt file based on its extension, and a boolean indicating
+// whether the path has a prompt file extension. Files with the .prompt extension may be in either the native or front
+// matter format, which is detected from their content.
+func promptFileFormatFromPath(path string) (FileFormat, bool) {
+	lowerPath := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lowerPath, jsonPromptFileExtension):
+		return JSONFormat, true
+	case strings.HasSuffix(lowerPath, tomlPromptFileExtension):
+		return TOMLFormat, true
+	case strings.HasSuffix(lowerPath, promptFileExtension):
+		return NativeFormat, true
+	default:
+		return NativeFormat, false
+	}
+}
+
+// isPromptFilePath returns true if the path has one of the prompt file extensions.
+func isPromptFilePath(path string) bool {
+	_, ok := promptFileFormatFromPath(path)
+	return ok
+}
+
+// decodePromptFile parses the prompt data in the specified format into a PromptFile without validating it, other than
+// checking for unknown fields when the strict option is set.
+func decodePromptFile(data []byte, format FileFormat, options promptFileOptions) (*PromptFile, error) {
+	promptFile := &PromptFile{}
+
+	var err error
+	switch format {
+	case JSONFormat:
+		err = json.Unmarshal(data, promptFile)
+	case TOMLFormat:
+		err = toml.Unmarshal(data, promptFile)
+	default:
+		if front, body, ok := splitFrontMatter(data); ok {
+			if err = parseFrontMatter(front, body, promptFile); err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		if options.edit {
+			err = promptFile.decodeDocument(data)
+		} else {
+			err = yaml.Unmarshal(data, promptFile)
+		}
+	}
+
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("failed to parse prompt file: %v", err),
+		}
+	}
+
+	if options.strict {
+		if err = checkUnknownFields(data, format); err != nil {
+			return nil, err
+		}
+	}
+
+	if format == JSONFormat || format == TOMLFormat {
+		promptFile.Format = format
+	}
+	promptFile.coerce = options.coerce
+	promptFile.mediaRoot = options.mediaRoot
+
+	return promptFile, nil
+}
+
+// encodePromptFile serializes the prompt file in the specified format.
+func encodePromptFile(pf *PromptFile, format FileFormat) ([]byte, error) {
+	if pf.resolved {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("prompt file %s has a profile or model alias resolved and cannot be serialized", pf.Name),
+		}
+	}
+
+	// The namespace is derived from where the prompt file is stored, so only the unqualified name is written
+	if pf.Namespace != "" {
+		unqualified := *pf
+		unqualified.Name = strings.TrimPrefix(pf.Name, pf.Namespace+"/")
+		pf = &unqualified
+	}
+
+	if format == FrontMatterFormat {
+		return pf.serializeFrontMatter()
+	}
+
+	if format == NativeFormat && pf.document != nil {
+		return pf.serializeDocument()
+	}
+
+	var b bytes.Buffer
+	var err error
+
+	switch format {
+	case JSONFormat:
+		encoder := json.NewEncoder(&b)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		err = encoder.Encode(pf)
+	case TOMLFormat:
+		err = toml.NewEncoder(&b).Encode(pf)
+	default:
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		err = encoder.Encode(pf)
+	}
+
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("failed to marshal prompt file: %v", err),
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// serializationFormatForPath returns the format to use when writing the prompt file to the path. Paths with the
+// .prompt.json or .prompt.toml extensions are written in that format, and paths with the .prompt extension are written
+// in the native or front matter format.
+func (pf *PromptFile) serializationFormatForPath(path string) FileFormat {
+	format, ok := promptFileFormatFromPath(filepath.Base(path))
+	if !ok || (format == NativeFormat && pf.Format == FrontMatterFormat) {
+		return pf.Format
+	}
+	return format
+}
@@ -0,0 +1,301 @@
+package dotprompt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewPromptFileFromFile_WithFormatExtension(t *testing.T) {
+	tests := []struct {
+		path           string
+		expectedName   string
+		expectedFormat FileFormat
+	}{
+		{"test-data/json-format.prompt.json", "json-format", JSONFormat},
+		{"test-data/toml-format.prompt.toml", "toml-format", TOMLFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			t.Parallel()
+			promptFile, err := NewPromptFileFromFile(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if promptFile.Name != test.expectedName {
+				t.Errorf("Expected name to be '%s', got '%s'", test.expectedName, promptFile.Name)
+			}
+
+			if promptFile.Format != test.expectedFormat {
+				t.Errorf("Expected format to be %v, got %v", test.expectedFormat, promptFile.Format)
+			}
+
+			if promptFile.Config.OutputFormat != Json {
+				t.Errorf("Expected output format to be json, got %s", promptFile.Config.OutputFormat.String())
+			}
+
+			if promptFile.Config.Temperature == nil || *promptFile.Config.Temperature != 0.9 {
+				t.Errorf("Expected temperature to be 0.9, got %v", promptFile.Config.Temperature)
+			}
+
+			if promptFile.Config.MaxTokens == nil || *promptFile.Config.MaxTokens != 500 {
+				t.Errorf("Expected max tokens to be 500, got %v", promptFile.Config.MaxTokens)
+			}
+
+			if promptFile.Config.Input.Parameters["style?"] != "string" {
+				t.Errorf("Expected optional parameter 'style', got %v", promptFile.Config.Input.Parameters)
+			}
+
+			userPrompt, err := promptFile.GetUserPrompt(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(userPrompt, "holiday to Malta") {
+				t.Errorf("Expected user prompt to use the default country, got '%s'", userPrompt)
+			}
+		})
+	}
+}
+
+func TestPromptFile_Serialize_RoundTripsAcrossFormats(t *testing.T) {
+	paths := []string{
+		"test-data/basic.prompt",
+		"test-data/basic-fsp.prompt",
+		"test-data/messages.prompt",
+		"test-data/with-tools.prompt",
+		"test-data/multimodal.prompt",
+		"test-data/model-parameters.prompt",
+		"test-data/with-overrides.prompt",
+		"test-data/datetime.prompt",
+		"test-data/coerce.prompt",
+		"test-data/json-format.prompt.json",
+		"test-data/toml-format.prompt.toml",
+	}
+	formats := []FileFormat{NativeFormat, JSONFormat, TOMLFormat}
+
+	for _, path := range paths {
+		original, err := NewPromptFileFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original.Format = NativeFormat
+
+		expected, err := original.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, format := range formats {
+			t.Run(filepath.Base(path)+format.extension(), func(t *testing.T) {
+				promptFile := *original
+				promptFile.Format = format
+
+				serialized, err := promptFile.Serialize()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				parsed, err := NewPromptFileWithFormat(original.Name, serialized, format)
+				if err != nil {
+					t.Fatalf("Failed to parse serialized prompt file: %v\n%s", err, serialized)
+				}
+
+				if parsed.Format != format {
+					t.Errorf("Expected format to be %v, got %v", format, parsed.Format)
+				}
+
+				// Compare the YAML serialization, as JSON and TOML decode numbers as float64 and int64 respectively
+				parsed.Format = NativeFormat
+				actual, err := parsed.Serialize()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(actual, expected) {
+					t.Errorf("Expected round trip to produce '%s', got '%s'", expected, actual)
+				}
+			})
+		}
+	}
+}
+
+func TestPromptFile_Serialize_WithJSONFormat(t *testing.T) {
+	promptFile := PromptFile{
+		Name:   "serialize-test",
+		Model:  "gpt-4o",
+		Format: JSONFormat,
+		Config: PromptConfig{
+			OutputFormat: Json,
+			Input: InputSchema{
+				Parameters: map[string]string{
+					"param1": "number",
+				},
+			},
+		},
+		Prompts: Prompts{
+			System: "system",
+			User:   "user <b>{{ param1 }}</b>",
+		},
+	}
+
+	expected := `{
+  "name": "serialize-test",
+  "model": "gpt-4o",
+  "config": {
+    "outputFormat": "json",
+    "input": {
+      "parameters": {
+        "param1": "number"
+      }
+    }
+  },
+  "prompts": {
+    "system": "system",
+    "user": "user <b>{{ param1 }}</b>"
+  }
+}
+`
+
+	serialized, err := promptFile.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(serialized) != expected {
+		t.Errorf("Expected serialized prompt file to be '%s', got '%s'", expected, serialized)
+	}
+}
+
+func TestPromptFile_ToFile_WithFormatExtension(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, extension := range []string{jsonPromptFileExtension, tomlPromptFileExtension} {
+		t.Run(extension, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "to-file-test"+extension)
+			if err = promptFile.ToFile(filePath); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := NewPromptFileFromFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedFormat, _ := promptFileFormatFromPath(filePath)
+			if loaded.Format != expectedFormat {
+				t.Errorf("Expected format to be %v, got %v", expectedFormat, loaded.Format)
+			}
+
+			if loaded.Prompts != promptFile.Prompts {
+				t.Errorf("Expected prompts %+v, got %+v", promptFile.Prompts, loaded.Prompts)
+			}
+		})
+	}
+}
+
+func TestNewPromptFileWithFormat_WithInvalidContent_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		format        FileFormat
+		expectedError string
+	}{
+		{"invalid-json", "{", JSONFormat, "failed to parse prompt file: unexpected end of JSON input"},
+		{"invalid-toml", "model = ", TOMLFormat, "failed to parse prompt file"},
+		{"invalid-output-format", `{"config": {"outputFormat": "xml"}}`, JSONFormat, "failed to parse prompt file"},
+		{"missing-user", "[prompts]\nsystem = \"Hello\"\n", TOMLFormat, "no user prompt template was provided in the prompt file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewPromptFileWithFormat(test.name, []byte(test.data), test.format)
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if !strings.HasPrefix(promptError.Error(), test.expectedError) {
+				t.Errorf("Expected error to start with '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestFSStore_Load_WithMixedFormats(t *testing.T) {
+	jsonContent, err := os.ReadFile("test-data/json-format.prompt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tomlContent, err := os.ReadFile("test-data/toml-format.prompt.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFSStore(fstest.MapFS{
+		"yaml.prompt":             {Data: []byte(minimalPrompt)},
+		"nested/json.PROMPT.JSON": {Data: jsonContent},
+		"toml.prompt.toml":        {Data: tomlContent},
+		"ignored.json":            {Data: jsonContent},
+	})
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"jsonpromptjson", "tomlprompttoml", "yamlprompt"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+}
+
+func TestOutputFormat_MarshalText(t *testing.T) {
+	tests := []struct {
+		format   OutputFormat
+		expected string
+	}{
+		{Text, "text"},
+		{Json, "json"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			t.Parallel()
+			text, err := test.format.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(text) != test.expected {
+				t.Errorf("Expected '%s', got '%s'", test.expected, text)
+			}
+
+			var format OutputFormat
+			if err = format.UnmarshalText([]byte(strings.ToUpper(test.expected))); err != nil {
+				t.Fatal(err)
+			}
+
+			if format != test.format {
+				t.Errorf("Expected %v, got %v", test.format, format)
+			}
+		})
+	}
+
+	if _, err := OutputFormat(99).MarshalText(); err == nil {
+		t.Error("Expected error for an invalid output format, got nil")
+	}
+}
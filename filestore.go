@@ -1,9 +1,12 @@
 package dotprompt
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 )
 
 const (
@@ -25,33 +28,33 @@ func (e FileStoreError) Error() string {
 
 // FileStore represents a file-based storage system for handling prompt files.
 type FileStore struct {
-	path string
+	path       string
+	options    loaderOptions
+	mu         sync.Mutex
+	loadErrors []LoadError
 }
 
 // Load retrieves all prompt files from the specified file path and returns a slice of PromptFile objects or an error.
+// Files and directories listed in a .promptignore file at the root of the path are skipped.
 func (f *FileStore) Load() ([]PromptFile, error) {
-	promptFiles := make([]PromptFile, 0)
-
-	err := filepath.Walk(f.path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return f.LoadContext(context.Background())
+}
 
-		if info.IsDir() {
-			return nil
-		}
+// LoadContext retrieves all prompt files from the specified file path in the same way as Load, stopping early if the
+// context is done.
+func (f *FileStore) LoadContext(ctx context.Context) ([]PromptFile, error) {
+	walker, err := newPromptWalker(os.DirFS(f.path), f.path, f.options, func(filePath string) (*PromptFile, error) {
+		return NewPromptFileFromFile(filepath.Join(f.path, filepath.FromSlash(filePath)), f.options.promptFile...)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		fileExtension := filepath.Ext(path)
-		if strings.ToLower(fileExtension) == promptFileExtension {
-			promptFile, promptFileErr := NewPromptFileFromFile(path)
-			if promptFileErr != nil {
-				return promptFileErr
-			}
-			promptFiles = append(promptFiles, *promptFile)
-		}
+	promptFiles, err := walker.walk(ctx)
 
-		return nil
-	})
+	f.mu.Lock()
+	f.loadErrors = walker.loadErrors
+	f.mu.Unlock()
 
 	if err != nil {
 		return nil, err
@@ -60,13 +63,22 @@ func (f *FileStore) Load() ([]PromptFile, error) {
 	return promptFiles, nil
 }
 
+// Errors returns the errors for the files skipped by the most recent call to Load, when the store was created with
+// WithLenient.
+func (f *FileStore) Errors() []LoadError {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.loadErrors)
+}
+
 // NewFileStore creates a new FileStore instance using the default file path ("prompts").
-func NewFileStore() (*FileStore, error) {
-	return NewFileStoreFromPath(defaultPath)
+func NewFileStore(options ...LoaderOption) (*FileStore, error) {
+	return NewFileStoreFromPath(defaultPath, options...)
 }
 
 // NewFileStoreFromPath creates a new FileStore instance from the specified directory path.
-func NewFileStoreFromPath(path string) (*FileStore, error) {
+func NewFileStoreFromPath(path string, options ...LoaderOption) (*FileStore, error) {
 	trimmedPath := strings.TrimSpace(path)
 	if trimmedPath == "" {
 		return nil, &FileStoreError{
@@ -90,5 +102,5 @@ func NewFileStoreFromPath(path string) (*FileStore, error) {
 		}
 	}
 
-	return &FileStore{path: trimmedPath}, nil
+	return &FileStore{path: trimmedPath, options: newLoaderOptions(options)}, nil
 }
@@ -0,0 +1,413 @@
+package dotprompt
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	frontMatterDelimiter = "---"
+	roleMarkerRegex      = regexp.MustCompile(`\{\{\s*role\s+(?:"([^"]*)"|'([^']*)')\s*\}\}`)
+	historyMarkerRegex   = regexp.MustCompile(`\{\{\s*history\s*\}\}`)
+	handlebarsBlockRegex = regexp.MustCompile(`\{\{~?\s*(?:[#^/>]\s*[^\s}~]*|else\b)`)
+	picoschemaKeyRegex   = regexp.MustCompile(`^([^(?]+)(\?)?(?:\(([^,)]+)(?:,[^)]*)?\))?$`)
+)
+
+// frontMatter represents the YAML front matter of a prompt file in the Genkit Dotprompt format. The parts of a prompt
+// file with no Genkit equivalent are held in keys prefixed with "dotprompt-go.", which Genkit treats as extension
+// fields.
+type frontMatter struct {
+	Name         string                     `yaml:"name,omitempty"`
+	Model        string                     `yaml:"model,omitempty"`
+	Config       frontMatterConfig          `yaml:"config,omitempty"`
+	Input        frontMatterInput           `yaml:"input,omitempty"`
+	Output       frontMatterOutput          `yaml:"output,omitempty"`
+	Tools        []string                   `yaml:"tools,omitempty"`
+	ToolDefs     []Tool                     `yaml:"dotprompt-go.tools,omitempty"`
+	FewShots     []FewShotPromptPair        `yaml:"dotprompt-go.fewShots,omitempty"`
+	Cache        *bool                      `yaml:"dotprompt-go.cache,omitempty"`
+	MaxMediaSize int                        `yaml:"dotprompt-go.maxMediaSize,omitempty"`
+	DateLayouts  []string                   `yaml:"dotprompt-go.dateLayouts,omitempty"`
+	TimeZone     string                     `yaml:"dotprompt-go.timeZone,omitempty"`
+	TimeZones    map[string]string          `yaml:"dotprompt-go.timeZones,omitempty"`
+	Coerce       bool                       `yaml:"dotprompt-go.coerce,omitempty"`
+	Overrides    map[string]ProfileOverride `yaml:"dotprompt-go.overrides,omitempty"`
+}
+
+// frontMatterConfig represents the model configuration in the front matter. Genkit passes the configuration to the
+// model as-is, so settings without a field of their own are held in Extra.
+type frontMatterConfig struct {
+	Temperature      *float32               `yaml:"temperature,omitempty"`
+	MaxOutputTokens  *int                   `yaml:"maxOutputTokens,omitempty"`
+	TopP             *float32               `yaml:"topP,omitempty"`
+	TopK             *int                   `yaml:"topK,omitempty"`
+	StopSequences    []string               `yaml:"stopSequences,omitempty"`
+	Seed             *int                   `yaml:"seed,omitempty"`
+	PresencePenalty  *float32               `yaml:"presencePenalty,omitempty"`
+	FrequencyPenalty *float32               `yaml:"frequencyPenalty,omitempty"`
+	ReasoningEffort  string                 `yaml:"reasoningEffort,omitempty"`
+	Extra            map[string]interface{} `yaml:",inline"`
+}
+
+type frontMatterInput struct {
+	Schema  map[string]interface{} `yaml:"schema,omitempty"`
+	Default map[string]interface{} `yaml:"default,omitempty"`
+}
+
+type frontMatterOutput struct {
+	Format *OutputFormat `yaml:"format,omitempty"`
+}
+
+// splitFrontMatter splits prompt data in the front matter format into its front matter and body, returning false if
+// the data does not start with a front matter block followed by a template body. This allows YAML prompt files which
+// start with a document marker to be loaded as before.
+func splitFrontMatter(data []byte) ([]byte, string, bool) {
+	content := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "\r\n", "\n")
+	trimmed := strings.TrimLeft(content, " \t\n")
+
+	if !strings.HasPrefix(trimmed, frontMatterDelimiter+"\n") {
+		return nil, "", false
+	}
+
+	// The front matter is padded with the lines before it so that line numbers in errors match the prompt data
+	padding := strings.Repeat("\n", strings.Count(content[:len(content)-len(trimmed)], "\n")+1)
+	content = trimmed[len(frontMatterDelimiter)+1:]
+
+	var front, body string
+	if strings.HasPrefix(content, frontMatterDelimiter+"\n") {
+		body = content[len(frontMatterDelimiter):]
+	} else {
+		end := strings.Index(content, "\n"+frontMatterDelimiter+"\n")
+		if end < 0 {
+			return nil, "", false
+		}
+		front, body = content[:end], content[end+len(frontMatterDelimiter)+1:]
+	}
+
+	if len(strings.TrimSpace(body)) == 0 {
+		return nil, "", false
+	}
+
+	return []byte(padding + front), body, true
+}
+
+// parseFrontMatter populates the prompt file from the front matter and template body of a prompt file in the front
+// matter format.
+func parseFrontMatter(front []byte, body string, pf *PromptFile) error {
+	var fm frontMatter
+	if err := yaml.Unmarshal(front, &fm); err != nil {
+		return &PromptError{
+			Message: fmt.Sprintf("failed to parse prompt file: %v", err),
+		}
+	}
+
+	parameters, err := parsePicoschema(fm.Input.Schema)
+	if err != nil {
+		return err
+	}
+
+	pf.Name = fm.Name
+	pf.Model = fm.Model
+	pf.Format = FrontMatterFormat
+	pf.Config = PromptConfig{
+		Temperature:      fm.Config.Temperature,
+		MaxTokens:        fm.Config.MaxOutputTokens,
+		TopP:             fm.Config.TopP,
+		TopK:             fm.Config.TopK,
+		Stop:             fm.Config.StopSequences,
+		Seed:             fm.Config.Seed,
+		PresencePenalty:  fm.Config.PresencePenalty,
+		FrequencyPenalty: fm.Config.FrequencyPenalty,
+		ReasoningEffort:  fm.Config.ReasoningEffort,
+		Extra:            fm.Config.Extra,
+		Cache:            fm.Cache,
+		Input: InputSchema{
+			Parameters:   parameters,
+			Default:      fm.Input.Default,
+			MaxMediaSize: fm.MaxMediaSize,
+			DateLayouts:  fm.DateLayouts,
+			TimeZone:     fm.TimeZone,
+			TimeZones:    fm.TimeZones,
+			Coerce:       fm.Coerce,
+		},
+	}
+	if fm.Output.Format != nil {
+		pf.Config.OutputFormat = *fm.Output.Format
+	}
+	pf.FewShots = fm.FewShots
+	pf.Tools = fm.ToolDefs
+	pf.Overrides = fm.Overrides
+
+	// The body is rendered using Liquid, so Handlebars block helpers, which would otherwise only fail when rendered, are
+	// rejected here
+	if match := handlebarsBlockRegex.FindString(body); match != "" {
+		return &PromptError{
+			Message: fmt.Sprintf("unsupported Handlebars syntax in template: %s}}, use Liquid tags such as {%% if %%} instead", match),
+		}
+	}
+
+	messages := parseTemplateBody(body)
+	switch {
+	case len(messages) == 1 && messages[0].Role == UserRole:
+		pf.Prompts = Prompts{User: messages[0].Content}
+	case len(messages) == 2 && messages[0].Role == SystemRole && messages[1].Role == UserRole:
+		pf.Prompts = Prompts{System: messages[0].Content, User: messages[1].Content}
+	default:
+		pf.Messages = messages
+	}
+
+	return nil
+}
+
+// parseTemplateBody splits the template body of a prompt file in the front matter format into messages at each role
+// and history marker. Any text before the first role marker or after the history marker is treated as a user message,
+// and the Genkit "model" role is mapped to the assistant role.
+func parseTemplateBody(body string) []MessageTemplate {
+	body = historyMarkerRegex.ReplaceAllString(body, `{{role "history"}}`)
+
+	messages := make([]MessageTemplate, 0)
+	addMessage := func(role string, content string) {
+		content = strings.TrimSpace(content)
+		if role == HistoryRole {
+			messages = append(messages, MessageTemplate{Role: HistoryRole})
+			role = UserRole
+		}
+		if len(content) > 0 {
+			messages = append(messages, MessageTemplate{Role: role, Content: content})
+		}
+	}
+
+	role := UserRole
+	start := 0
+	for _, match := range roleMarkerRegex.FindAllStringSubmatchIndex(body, -1) {
+		addMessage(role, body[start:match[0]])
+
+		if match[2] >= 0 {
+			role = body[match[2]:match[3]]
+		} else {
+			role = body[match[4]:match[5]]
+		}
+		if role == "model" {
+			role = AssistantRole
+		}
+		start = match[1]
+	}
+	addMessage(role, body[start:])
+
+	return messages
+}
+
+// parsePicoschema converts an input schema written in Picoschema, or as a JSON Schema object, into the parameters of
+// an InputSchema. Optional fields are marked with a `?` suffix in both formats.
+func parsePicoschema(schema map[string]interface{}) (map[string]string, error) {
+	if len(schema) == 0 {
+		return nil, nil
+	}
+
+	if schemaType, ok := schema["type"].(string); ok && schemaType == "object" {
+		return parseJsonSchemaProperties(schema)
+	}
+
+	parameters := make(map[string]string, len(schema))
+	for key, value := range schema {
+		match := picoschemaKeyRegex.FindStringSubmatch(strings.TrimSpace(key))
+		if match == nil {
+			return nil, &PromptError{
+				Message: fmt.Sprintf("invalid input schema field: %s", key),
+			}
+		}
+
+		name := strings.TrimSpace(match[1]) + match[2]
+		schemaType := strings.TrimSpace(match[3])
+		if len(schemaType) == 0 {
+			switch typed := value.(type) {
+			case string:
+				schemaType, _, _ = strings.Cut(typed, ",")
+			case map[string]interface{}:
+				schemaType = "object"
+			default:
+				return nil, &PromptError{
+					Message: fmt.Sprintf("invalid input schema type for parameter %s", key),
+				}
+			}
+		}
+
+		paramType, err := parameterTypeFromSchema(name, strings.TrimSpace(schemaType))
+		if err != nil {
+			return nil, err
+		}
+		parameters[name] = paramType
+	}
+
+	return parameters, nil
+}
+
+// parseJsonSchemaProperties converts the properties of a JSON Schema object into the parameters of an InputSchema.
+func parseJsonSchemaProperties(schema map[string]interface{}) (map[string]string, error) {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	required := make([]string, 0)
+	if values, ok := schema["required"].([]interface{}); ok {
+		for _, value := range values {
+			required = append(required, fmt.Sprint(value))
+		}
+	}
+
+	parameters := make(map[string]string, len(properties))
+	for name, property := range properties {
+		definition, _ := property.(map[string]interface{})
+		schemaType, _ := definition["type"].(string)
+
+		paramType, err := parameterTypeFromSchema(name, schemaType)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(required, name) {
+			name += "?"
+		}
+		parameters[name] = paramType
+	}
+
+	return parameters, nil
+}
+
+// parameterTypeFromSchema maps a Picoschema or JSON Schema type onto the equivalent parameter data type. The data
+// types without a schema equivalent, such as datetime and image, are accepted as they are.
+func parameterTypeFromSchema(name string, schemaType string) (string, error) {
+	switch schemaType {
+	case "string", "datetime", "image", "file":
+		return schemaType, nil
+	case "enum":
+		return "string", nil
+	case "number", "integer":
+		return "number", nil
+	case "boolean", "bool":
+		return "bool", nil
+	case "object", "array", "any":
+		return "object", nil
+	default:
+		return "", &PromptError{
+			Message: fmt.Sprintf("invalid data type for parameter %s: %s", name, schemaType),
+		}
+	}
+}
+
+// schemaTypeFromParameter maps a parameter data type onto the equivalent Picoschema type.
+func schemaTypeFromParameter(paramType string) string {
+	switch paramType {
+	case "bool":
+		return "boolean"
+	case "object":
+		return "any"
+	default:
+		return paramType
+	}
+}
+
+// serializeFrontMatter serializes the prompt file into the front matter format.
+func (pf *PromptFile) serializeFrontMatter() ([]byte, error) {
+	fm := frontMatter{
+		Name:  pf.Name,
+		Model: pf.Model,
+		Config: frontMatterConfig{
+			Temperature:      pf.Config.Temperature,
+			MaxOutputTokens:  pf.Config.MaxTokens,
+			TopP:             pf.Config.TopP,
+			TopK:             pf.Config.TopK,
+			StopSequences:    pf.Config.Stop,
+			Seed:             pf.Config.Seed,
+			PresencePenalty:  pf.Config.PresencePenalty,
+			FrequencyPenalty: pf.Config.FrequencyPenalty,
+			ReasoningEffort:  pf.Config.ReasoningEffort,
+			Extra:            pf.Config.Extra,
+		},
+		Input: frontMatterInput{
+			Default: pf.Config.Input.Default,
+		},
+		ToolDefs:     pf.Tools,
+		FewShots:     pf.FewShots,
+		Cache:        pf.Config.Cache,
+		MaxMediaSize: pf.Config.Input.MaxMediaSize,
+		DateLayouts:  pf.Config.Input.DateLayouts,
+		TimeZone:     pf.Config.Input.TimeZone,
+		TimeZones:    pf.Config.Input.TimeZones,
+		Coerce:       pf.Config.Input.Coerce,
+		Overrides:    pf.Overrides,
+	}
+
+	if pf.Config.OutputFormat != Text {
+		outputFormat := pf.Config.OutputFormat
+		fm.Output.Format = &outputFormat
+	}
+
+	if len(pf.Config.Input.Parameters) > 0 {
+		fm.Input.Schema = make(map[string]interface{}, len(pf.Config.Input.Parameters))
+		for name, paramType := range pf.Config.Input.Parameters {
+			fm.Input.Schema[name] = schemaTypeFromParameter(paramType)
+		}
+	}
+
+	for _, tool := range pf.Tools {
+		fm.Tools = append(fm.Tools, tool.Name)
+	}
+
+	body, err := pf.templateBody()
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(frontMatterDelimiter + "\n")
+
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&fm); err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("failed to marshal prompt file: %v", err),
+		}
+	}
+
+	b.WriteString(frontMatterDelimiter + "\n")
+	b.WriteString(body)
+
+	return b.Bytes(), nil
+}
+
+// templateBody returns the template body of the prompt file in the front matter format, with a role marker before
+// each message. Returns an error if a message uses a field which cannot be represented in the body.
+func (pf *PromptFile) templateBody() (string, error) {
+	templates := pf.Messages
+	if len(templates) == 0 {
+		templates = make([]MessageTemplate, 0, 2)
+		if len(pf.Prompts.System) > 0 {
+			templates = append(templates, MessageTemplate{Role: SystemRole, Content: pf.Prompts.System})
+		}
+		templates = append(templates, MessageTemplate{Role: UserRole, Content: pf.Prompts.User})
+	}
+
+	sections := make([]string, 0, len(templates))
+	for i, template := range templates {
+		if len(template.Name) > 0 || len(template.ToolCallID) > 0 || template.Role == ToolRole {
+			return "", &PromptError{
+				Message: fmt.Sprintf("message %d cannot be written in the front matter format", i),
+			}
+		}
+
+		switch template.Role {
+		case HistoryRole:
+			sections = append(sections, "{{history}}")
+		case AssistantRole:
+			sections = append(sections, fmt.Sprintf("{{role \"model\"}}\n%s", strings.TrimSpace(template.Content)))
+		default:
+			sections = append(sections, fmt.Sprintf("{{role \"%s\"}}\n%s", template.Role, strings.TrimSpace(template.Content)))
+		}
+	}
+
+	return strings.Join(sections, "\n\n") + "\n", nil
+}
@@ -0,0 +1,304 @@
+package dotprompt
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewPromptFileFromFile_WithFrontMatter(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/genkit.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if promptFile.Format != FrontMatterFormat {
+		t.Errorf("Expected format to be FrontMatterFormat, got %v", promptFile.Format)
+	}
+
+	if promptFile.Name != "genkit" {
+		t.Errorf("Expected name to be 'genkit', got '%s'", promptFile.Name)
+	}
+
+	if promptFile.Model != "googleai/gemini-1.5-flash" {
+		t.Errorf("Expected model to be 'googleai/gemini-1.5-flash', got '%s'", promptFile.Model)
+	}
+
+	if promptFile.Config.Temperature == nil || *promptFile.Config.Temperature != 0.7 {
+		t.Errorf("Expected temperature to be 0.7, got %v", promptFile.Config.Temperature)
+	}
+
+	if promptFile.Config.MaxTokens == nil || *promptFile.Config.MaxTokens != 500 {
+		t.Errorf("Expected max tokens to be 500, got %v", promptFile.Config.MaxTokens)
+	}
+
+	if promptFile.Config.OutputFormat != Json {
+		t.Errorf("Expected output format to be json, got %s", promptFile.Config.OutputFormat.String())
+	}
+
+	expectedParameters := map[string]string{
+		"topic":       "string",
+		"audience?":   "string",
+		"tags?":       "object",
+		"paragraphs?": "number",
+	}
+	if !maps.Equal(promptFile.Config.Input.Parameters, expectedParameters) {
+		t.Errorf("Expected parameters %v, got %v", expectedParameters, promptFile.Config.Input.Parameters)
+	}
+
+	if promptFile.Config.Input.Default["audience"] != "developers" {
+		t.Errorf("Expected default audience to be 'developers', got %v", promptFile.Config.Input.Default["audience"])
+	}
+
+	if promptFile.Prompts.System != "You are a helpful research assistant." {
+		t.Errorf("Expected system prompt, got '%s'", promptFile.Prompts.System)
+	}
+
+	userPrompt, err := promptFile.GetUserPrompt(map[string]interface{}{"topic": "Go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if userPrompt != "Explain Go to developers." {
+		t.Errorf("Expected user prompt to be 'Explain Go to developers.', got '%s'", userPrompt)
+	}
+}
+
+func TestNewPromptFile_WithFrontMatterBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		prompts  Prompts
+		messages []MessageTemplate
+	}{
+		{
+			"no-role-markers",
+			"Hello {{ name }}\n",
+			Prompts{User: "Hello {{ name }}"},
+			nil,
+		},
+		{
+			"single-quoted-roles",
+			"{{ role 'system' }}\nBe brief\n{{ role 'user' }}\nHello\n",
+			Prompts{System: "Be brief", User: "Hello"},
+			nil,
+		},
+		{
+			"history-and-model-turns",
+			"{{role \"system\"}}\nBe brief\n{{role \"user\"}}\nHi\n{{role \"model\"}}\nHello!\n{{history}}\n{{role \"user\"}}\nBye\n",
+			Prompts{},
+			[]MessageTemplate{
+				{Role: SystemRole, Content: "Be brief"},
+				{Role: UserRole, Content: "Hi"},
+				{Role: AssistantRole, Content: "Hello!"},
+				{Role: HistoryRole},
+				{Role: UserRole, Content: "Bye"},
+			},
+		},
+		{
+			"text-after-history",
+			"{{history}}\n{{ question }}\n",
+			Prompts{},
+			[]MessageTemplate{
+				{Role: HistoryRole},
+				{Role: UserRole, Content: "{{ question }}"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			promptFile, err := NewPromptFile(test.name, []byte("---\nmodel: test\n---\n"+test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if promptFile.Prompts != test.prompts {
+				t.Errorf("Expected prompts %+v, got %+v", test.prompts, promptFile.Prompts)
+			}
+
+			if !reflect.DeepEqual(promptFile.Messages, test.messages) {
+				t.Errorf("Expected messages %+v, got %+v", test.messages, promptFile.Messages)
+			}
+		})
+	}
+}
+
+func TestNewPromptFile_WithFrontMatterJsonSchema(t *testing.T) {
+	data := "---\ninput:\n  schema:\n    type: object\n    properties:\n      topic:\n        type: string\n      count:\n        type: integer\n    required: [topic]\n---\n{{ topic }}\n"
+
+	promptFile, err := NewPromptFile("json-schema", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"topic": "string", "count?": "number"}
+	if !maps.Equal(promptFile.Config.Input.Parameters, expected) {
+		t.Errorf("Expected parameters %v, got %v", expected, promptFile.Config.Input.Parameters)
+	}
+}
+
+func TestNewPromptFile_WithInvalidFrontMatter_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		expectedError string
+	}{
+		{"invalid-yaml", "---\nmodel: [\n---\nHello\n", "failed to parse prompt file"},
+		{"invalid-type", "---\ninput:\n  schema:\n    topic: uuid\n---\nHello\n", "invalid data type for parameter topic: uuid"},
+		{"invalid-role", "---\nmodel: test\n---\n{{role \"narrator\"}}\nHello\n", "invalid role for message 0: narrator"},
+		{"missing-user", "---\nmodel: test\n---\n{{role \"system\"}}\nHello\n", "no user message or history placeholder"},
+		{"handlebars-if", "---\nmodel: test\n---\n{{#if topic}}Hello{{/if}}\n", "unsupported Handlebars syntax in template: {{#if}}"},
+		{"handlebars-each", "---\nmodel: test\n---\n{{ #each items }}{{this}}{{/each}}\n", "unsupported Handlebars syntax in template: {{ #each}}"},
+		{"handlebars-else", "---\nmodel: test\n---\n{% if topic %}A{{else}}B{% endif %}\n", "unsupported Handlebars syntax in template: {{else}}"},
+		{"handlebars-partial", "---\nmodel: test\n---\n{{> header}}\nHello\n", "unsupported Handlebars syntax in template: {{> header}}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewPromptFile(test.name, []byte(test.data))
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if !strings.HasPrefix(promptError.Error(), test.expectedError) {
+				t.Errorf("Expected error to start with '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestNewPromptFile_WithYamlDocumentMarker_UsesNativeFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"start-marker", "---\n" + minimalPrompt},
+		{"start-and-end-markers", "---\n" + minimalPrompt + "---\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			promptFile, err := NewPromptFile(test.name, []byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if promptFile.Format != NativeFormat {
+				t.Errorf("Expected format to be NativeFormat, got %v", promptFile.Format)
+			}
+
+			if promptFile.Prompts.User != "Hello" {
+				t.Errorf("Expected user prompt to be 'Hello', got '%s'", promptFile.Prompts.User)
+			}
+		})
+	}
+}
+
+func TestPromptFile_Serialize_WithFrontMatterFormat(t *testing.T) {
+	temperature := float32(0.5)
+	promptFile := PromptFile{
+		Name:   "serialize-test",
+		Model:  "gpt-4o",
+		Format: FrontMatterFormat,
+		Config: PromptConfig{
+			Temperature:  &temperature,
+			OutputFormat: Json,
+			Input: InputSchema{
+				Parameters: map[string]string{
+					"param1":  "number",
+					"param2?": "bool",
+				},
+			},
+		},
+		Prompts: Prompts{
+			System: "system",
+			User:   "user {{ param1 }}",
+		},
+	}
+
+	expected := "---\nname: serialize-test\nmodel: gpt-4o\nconfig:\n  temperature: 0.5\ninput:\n  schema:\n    param1: number\n    param2?: boolean\noutput:\n  format: json\n---\n{{role \"system\"}}\nsystem\n\n{{role \"user\"}}\nuser {{ param1 }}\n"
+
+	serialized, err := promptFile.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(serialized) != expected {
+		t.Errorf("Expected serialized prompt file to be '%s', got '%s'", expected, serialized)
+	}
+}
+
+func TestPromptFile_Serialize_WithFrontMatterFormat_RoundTrips(t *testing.T) {
+	tests := []string{
+		"test-data/genkit.prompt",
+		"test-data/messages.prompt",
+		"test-data/basic-fsp.prompt",
+		"test-data/with-tools.prompt",
+		"test-data/multimodal.prompt",
+		"test-data/model-parameters.prompt",
+		"test-data/with-overrides.prompt",
+		"test-data/datetime.prompt",
+		"test-data/coerce.prompt",
+	}
+
+	for _, path := range tests {
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+			original, err := NewPromptFileFromFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			original.Format = FrontMatterFormat
+
+			serialized, err := original.Serialize()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := NewPromptFile(original.Name, serialized)
+			if err != nil {
+				t.Fatalf("Failed to parse serialized prompt file: %v\n%s", err, serialized)
+			}
+
+			original.Source = SourceMetadata{}
+			for _, promptFile := range []*PromptFile{original, parsed} {
+				promptFile.Prompts.System = strings.TrimSpace(promptFile.Prompts.System)
+				promptFile.Prompts.User = strings.TrimSpace(promptFile.Prompts.User)
+			}
+
+			if !reflect.DeepEqual(original, parsed) {
+				t.Errorf("Expected round trip to produce %+v, got %+v", original, parsed)
+			}
+		})
+	}
+}
+
+func TestPromptFile_Serialize_WithUnsupportedMessage_ReturnsError(t *testing.T) {
+	promptFile := PromptFile{
+		Format: FrontMatterFormat,
+		Messages: []MessageTemplate{
+			{Role: UserRole, Content: "What is the weather?"},
+			{Role: ToolRole, Content: "Sunny", ToolCallID: "call-1"},
+		},
+	}
+
+	_, err := promptFile.Serialize()
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expected := "message 1 cannot be written in the front matter format"
+	if promptError.Error() != expected {
+		t.Errorf("Expected error '%s', got '%s'", expected, promptError.Error())
+	}
+}
@@ -1,62 +1,81 @@
 package dotprompt
 
 import (
+	"context"
 	"io/fs"
 	"path"
-	"path/filepath"
-	"strings"
+	"slices"
+	"sync"
 )
 
 // FSStore represents a file-system-based storage system for handling prompt files.
 type FSStore struct {
-	dirFs fs.ReadDirFS
+	dirFs      fs.ReadDirFS
+	options    loaderOptions
+	mu         sync.Mutex
+	loadErrors []LoadError
 }
 
 // NewFSStore creates a new FSStore instance using the provided fs.ReadDirFS for reading and managing prompt files.
-func NewFSStore(dirFs fs.ReadDirFS) *FSStore {
+func NewFSStore(dirFs fs.ReadDirFS, options ...LoaderOption) *FSStore {
 	return &FSStore{
-		dirFs: dirFs,
+		dirFs:   dirFs,
+		options: newLoaderOptions(options),
 	}
 }
 
 // Load retrieves all prompt files from the root directory and its subdirectories in the file system storage.
+// Files and directories listed in a .promptignore file at the root are skipped.
 // Returns a slice of PromptFile and an error if any issue occurs during the loading process.
 func (f *FSStore) Load() ([]PromptFile, error) {
-	return f.loadFromDir(".")
+	return f.LoadContext(context.Background())
 }
 
-// loadFromDir recursively loads prompt files from the specified directory path and its subdirectories.
-// Returns a slice of PromptFile and an error if reading directories or files fails.
-func (f *FSStore) loadFromDir(dirPath string) ([]PromptFile, error) {
-	entries, err := fs.ReadDir(f.dirFs, dirPath)
+// LoadContext retrieves all prompt files from the file system storage in the same way as Load, stopping early if the
+// context is done.
+func (f *FSStore) LoadContext(ctx context.Context) ([]PromptFile, error) {
+	walker, err := newPromptWalker(f.dirFs, "", f.options, func(filePath string) (*PromptFile, error) {
+		file, readErr := fs.ReadFile(f.dirFs, filePath)
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		source := SourceMetadata{Path: filePath, Loader: FSSource}
+		if info, statErr := fs.Stat(f.dirFs, filePath); statErr == nil {
+			source.ModTime = info.ModTime()
+		}
+
+		// Prompt files are named from the full file name, such as "basicprompt", unless namespaces are used, in which
+		// case the extension is removed so that names are qualified in the same way as those of a FileStore
+		name := path.Base(filePath)
+		if f.options.namespaces {
+			name = promptFileNameFromPath(filePath)
+		}
+
+		return newPromptFileFromSource(name, file, source, f.options.promptFile...)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var promptFiles []PromptFile
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			files, loadErr := f.loadFromDir(path.Join(dirPath, entry.Name()))
-			if loadErr != nil {
-				return nil, loadErr
-			}
-			promptFiles = append(promptFiles, files...)
-		} else {
-			if strings.ToLower(filepath.Ext(entry.Name())) != promptFileExtension {
-				continue
-			}
-			file, readErr := fs.ReadFile(f.dirFs, path.Join(dirPath, entry.Name()))
-			if readErr != nil {
-				return nil, readErr
-			}
-			pf, pfErr := NewPromptFile(entry.Name(), file)
-			if pfErr != nil {
-				return nil, pfErr
-			}
-			promptFiles = append(promptFiles, *pf)
-		}
+	promptFiles, err := walker.walk(ctx)
+
+	f.mu.Lock()
+	f.loadErrors = walker.loadErrors
+	f.mu.Unlock()
+
+	if err != nil {
+		return nil, err
 	}
 
 	return promptFiles, nil
 }
+
+// Errors returns the errors for the files skipped by the most recent call to Load, when the store was created with
+// WithLenient.
+func (f *FSStore) Errors() []LoadError {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.loadErrors)
+}
@@ -4,7 +4,9 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"slices"
 	"testing"
+	"testing/fstest"
 )
 
 //go:embed file-store-tests
@@ -46,6 +48,78 @@ func TestFSStore_Load_WithInvalidFiles(t *testing.T) {
 	}
 }
 
+func TestFSStore_Load_NamesFromFileName(t *testing.T) {
+	store := NewFSStore(validFs)
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"another-example-with-name", "basicprompt"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+}
+
+func TestFSStore_Load_WithNamespaces(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root.prompt":                {Data: []byte(minimalPrompt)},
+		"support/summarise.prompt":   {Data: []byte(minimalPrompt)},
+		"sales/summarise.prompt":     {Data: []byte(minimalPrompt)},
+		"sales/EU Team/Pitch.prompt": {Data: []byte("name: Cold Pitch\n" + minimalPrompt)},
+	}
+
+	promptFiles, err := NewFSStore(fsys, WithNamespaces()).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"root", "sales/eu-team/cold-pitch", "sales/summarise", "support/summarise"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+
+	if _, err = NewManagerFromLoader(NewFSStore(fsys)); err == nil {
+		t.Error("Expected duplicate names without namespaces, got none")
+	}
+}
+
+func TestFSStore_Load_WithNamespaces_SerializesUnqualifiedName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"support/summarise.prompt": {Data: []byte(minimalPrompt)},
+	}
+
+	promptFiles, err := NewFSStore(fsys, WithNamespaces()).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := promptFiles[0].Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewPromptFile("reloaded", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reloaded.Name != "summarise" {
+		t.Errorf("Expected name 'summarise', got '%s'", reloaded.Name)
+	}
+
+	fsys["support/summarise.prompt"] = &fstest.MapFile{Data: content}
+	promptFiles, err = NewFSStore(fsys, WithNamespaces()).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if promptFiles[0].Name != "support/summarise" {
+		t.Errorf("Expected name 'support/summarise', got '%s'", promptFiles[0].Name)
+	}
+}
+
 func ExampleNewManagerFromLoader_withFSStore() {
 	// Create a new FSStore instance using the embedded file system, see https://pkg.go.dev/embed for more details
 	store := NewFSStore(promptFs)
@@ -0,0 +1,179 @@
+package dotprompt
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GitStoreError represents an error encountered when reading prompt files from a git repository.
+// It contains a message describing the error and an optional underlying error.
+type GitStoreError struct {
+	Message string
+	Err     error
+}
+
+// Error returns the error message contained in the GitStoreError.
+func (e GitStoreError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e GitStoreError) Unwrap() error {
+	return e.Err
+}
+
+// GitStoreOption configures optional behaviour of a GitStore.
+type GitStoreOption func(s *GitStore)
+
+// WithGitDirectory restricts the GitStore to prompt files within the specified directory of the repository, such as
+// "prompts". By default, prompt files are read from the whole repository.
+func WithGitDirectory(dir string) GitStoreOption {
+	return func(s *GitStore) {
+		s.dir = strings.Trim(path.Clean(filepath.ToSlash(dir)), "/")
+	}
+}
+
+// GitStore represents a storage system which reads prompt files from a git repository as of a specific revision,
+// without checking out the working tree. The revision can be a branch, tag, commit hash, or any other expression
+// understood by git, such as "HEAD~1", and is resolved each time the store is loaded. Each loaded PromptFile has its
+// Revision set to the hash of the commit it was read from.
+type GitStore struct {
+	repository *git.Repository
+	revision   string
+	dir        string
+}
+
+// NewGitStore creates a new GitStore which reads prompt files from the git repository at repoPath, which may be a
+// working tree or a bare repository, as of the specified revision.
+func NewGitStore(repoPath string, revision string, options ...GitStoreOption) (*GitStore, error) {
+	repository, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, &GitStoreError{
+			Message: fmt.Sprintf("failed to open git repository %s: %v", repoPath, err),
+			Err:     err,
+		}
+	}
+
+	return NewGitStoreFromRepository(repository, revision, options...)
+}
+
+// NewGitStoreFromRepository creates a new GitStore which reads prompt files from an already opened git repository,
+// such as an in-memory clone, as of the specified revision.
+func NewGitStoreFromRepository(repository *git.Repository, revision string, options ...GitStoreOption) (*GitStore, error) {
+	if repository == nil {
+		return nil, &GitStoreError{
+			Message: "The specified repository is nil",
+		}
+	}
+
+	trimmedRevision := strings.TrimSpace(revision)
+	if trimmedRevision == "" {
+		return nil, &GitStoreError{
+			Message: "The specified revision is empty",
+		}
+	}
+
+	store := &GitStore{
+		repository: repository,
+		revision:   trimmedRevision,
+	}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	if store.dir == "." {
+		store.dir = ""
+	}
+
+	return store, nil
+}
+
+// Load resolves the revision and retrieves all prompt files from the tree of its commit. Returns a slice of
+// PromptFile, each with its Revision set to the commit hash, or an error.
+func (s *GitStore) Load() ([]PromptFile, error) {
+	return s.LoadContext(context.Background())
+}
+
+// LoadContext resolves the revision and retrieves all prompt files from the tree of its commit in the same way as
+// Load, stopping early if the context is done.
+func (s *GitStore) LoadContext(ctx context.Context) ([]PromptFile, error) {
+	hash, err := s.repository.ResolveRevision(plumbing.Revision(s.revision))
+	if err != nil {
+		return nil, &GitStoreError{
+			Message: fmt.Sprintf("failed to resolve revision %s: %v", s.revision, err),
+			Err:     err,
+		}
+	}
+
+	commit, err := s.repository.CommitObject(*hash)
+	if err != nil {
+		return nil, &GitStoreError{
+			Message: fmt.Sprintf("failed to read commit %s: %v", hash, err),
+			Err:     err,
+		}
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, &GitStoreError{
+			Message: fmt.Sprintf("failed to read tree of commit %s: %v", hash, err),
+			Err:     err,
+		}
+	}
+
+	if s.dir != "" {
+		if tree, err = tree.Tree(s.dir); err != nil {
+			return nil, &GitStoreError{
+				Message: fmt.Sprintf("failed to read %s at commit %s: %v", s.dir, hash, err),
+				Err:     err,
+			}
+		}
+	}
+
+	promptFiles := make([]PromptFile, 0)
+	err = tree.Files().ForEach(func(file *object.File) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		if !isPromptFilePath(file.Name) {
+			return nil
+		}
+
+		content, readErr := file.Contents()
+		if readErr != nil {
+			return &GitStoreError{
+				Message: fmt.Sprintf("failed to read %s at commit %s: %v", file.Name, hash, readErr),
+				Err:     readErr,
+			}
+		}
+
+		source := SourceMetadata{
+			Path:    path.Join(s.dir, file.Name),
+			Loader:  GitSource,
+			ModTime: commit.Committer.When,
+		}
+
+		promptFile, promptFileErr := newPromptFileFromSource(promptFileNameFromPath(file.Name), []byte(content), source)
+		if promptFileErr != nil {
+			return promptFileErr
+		}
+		promptFile.Revision = hash.String()
+		promptFiles = append(promptFiles, *promptFile)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return promptFiles, nil
+}
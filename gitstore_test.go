@@ -0,0 +1,241 @@
+package dotprompt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testGitRepository is a throwaway git repository in a temporary directory, used to commit prompt files.
+type testGitRepository struct {
+	t          *testing.T
+	path       string
+	repository *git.Repository
+	worktree   *git.Worktree
+}
+
+func newTestGitRepository(t *testing.T) *testGitRepository {
+	t.Helper()
+
+	repoPath := t.TempDir()
+	repository, err := git.PlainInit(repoPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repository.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testGitRepository{t: t, path: repoPath, repository: repository, worktree: worktree}
+}
+
+// commit writes the files, copying the content of each from the specified source file, and commits them.
+func (r *testGitRepository) commit(message string, files map[string]string) plumbing.Hash {
+	r.t.Helper()
+
+	for name, source := range files {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			r.t.Fatal(err)
+		}
+
+		target := filepath.Join(r.path, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			r.t.Fatal(err)
+		}
+
+		if err = os.WriteFile(target, content, 0644); err != nil {
+			r.t.Fatal(err)
+		}
+
+		if _, err = r.worktree.Add(name); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+
+	hash, err := r.worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	return hash
+}
+
+func TestGitStore_Load(t *testing.T) {
+	repo := newTestGitRepository(t)
+	first := repo.commit("Add basic prompt", map[string]string{
+		"prompts/basic.prompt": "file-store-tests/basic.prompt",
+		"README.md":            "README.md",
+	})
+
+	if _, err := repo.repository.CreateTag("v1", first, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		Message: "First release",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	second := repo.commit("Add another prompt", map[string]string{
+		"prompts/sub-dir/another-with-name.prompt": "file-store-tests/sub-dir/another-with-name.prompt",
+	})
+
+	tests := []struct {
+		name             string
+		revision         string
+		expectedRevision plumbing.Hash
+		expectedNames    []string
+	}{
+		{"head", "HEAD", second, []string{"another-example-with-name", "basic"}},
+		{"branch", "master", second, []string{"another-example-with-name", "basic"}},
+		{"annotated-tag", "v1", first, []string{"basic"}},
+		{"parent", "HEAD~1", first, []string{"basic"}},
+		{"commit-hash", first.String(), first, []string{"basic"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store, err := NewGitStore(repo.path, test.revision)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			promptFiles, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if names := promptFileNames(promptFiles); !slices.Equal(names, test.expectedNames) {
+				t.Errorf("Expected prompt files %v, got %v", test.expectedNames, names)
+			}
+
+			for _, promptFile := range promptFiles {
+				if promptFile.Revision != test.expectedRevision.String() {
+					t.Errorf("Expected revision %s, got %s", test.expectedRevision, promptFile.Revision)
+				}
+			}
+		})
+	}
+}
+
+func TestGitStore_Load_IgnoresWorkingTree(t *testing.T) {
+	repo := newTestGitRepository(t)
+	repo.commit("Add basic prompt", map[string]string{"basic.prompt": "file-store-tests/basic.prompt"})
+
+	// An uncommitted, invalid prompt file in the working tree must not be read
+	if err := os.WriteFile(filepath.Join(repo.path, "invalid.prompt"), []byte("not: [valid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewGitStore(repo.path, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 1 {
+		t.Errorf("Expected 1 prompt file, got %d", len(promptFiles))
+	}
+}
+
+func TestGitStore_Load_WithGitDirectory(t *testing.T) {
+	repo := newTestGitRepository(t)
+	repo.commit("Add prompts", map[string]string{
+		"prompts/basic.prompt":    "file-store-tests/basic.prompt",
+		"examples/basic.prompt":   "file-store-tests/basic.prompt",
+		"examples/example.prompt": "prompts/example.prompt",
+	})
+
+	store, err := NewGitStore(repo.path, "HEAD", WithGitDirectory("prompts/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := promptFileNames(promptFiles); !slices.Equal(names, []string{"basic"}) {
+		t.Errorf("Expected prompt files [basic], got %v", names)
+	}
+
+	missing, err := NewGitStore(repo.path, "HEAD", WithGitDirectory("missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = missing.Load()
+
+	var storeError *GitStoreError
+	if !errors.As(err, &storeError) {
+		t.Fatalf("Expected GitStoreError, got %T", err)
+	}
+}
+
+func TestGitStore_Load_WithUnknownRevision_ReturnsError(t *testing.T) {
+	repo := newTestGitRepository(t)
+	repo.commit("Add basic prompt", map[string]string{"basic.prompt": "file-store-tests/basic.prompt"})
+
+	store, err := NewGitStore(repo.path, "does-not-exist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Load()
+
+	var storeError *GitStoreError
+	if !errors.As(err, &storeError) {
+		t.Fatalf("Expected GitStoreError, got %T", err)
+	}
+
+	if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		t.Errorf("Expected reference not found error, got %v", storeError.Err)
+	}
+}
+
+func TestNewGitStore_WithInvalidArguments(t *testing.T) {
+	repo := newTestGitRepository(t)
+
+	tests := []struct {
+		name          string
+		path          string
+		revision      string
+		expectedError string
+	}{
+		{"empty-revision", repo.path, " ", "The specified revision is empty"},
+		{"not-a-repository", t.TempDir(), "HEAD", "failed to open git repository"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewGitStore(test.path, test.revision)
+
+			var storeError *GitStoreError
+			if !errors.As(err, &storeError) {
+				t.Fatalf("Expected GitStoreError, got %T", err)
+			}
+
+			if !strings.HasPrefix(storeError.Error(), test.expectedError) {
+				t.Errorf("Expected error to start with '%s', got '%s'", test.expectedError, storeError.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,551 @@
+package dotprompt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHTTPTimeout     = 30 * time.Second
+	defaultHTTPMaxBodySize = 10 << 20
+	manifestFileName       = "manifest.json"
+	manifestETagFileName   = "etags.json"
+)
+
+// HTTPStoreError represents an error encountered when fetching prompt files from a prompt service.
+// It contains a message describing the error, the HTTP status code if a response was received, and an optional
+// underlying error.
+type HTTPStoreError struct {
+	Message    string
+	StatusCode int
+	Err        error
+}
+
+// Error returns the error message contained in the HTTPStoreError.
+func (e HTTPStoreError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e HTTPStoreError) Unwrap() error {
+	return e.Err
+}
+
+// unreachable returns true if the error indicates that the prompt service could not be reached or failed, rather
+// than rejecting the request.
+func (e HTTPStoreError) unreachable() bool {
+	return e.StatusCode == 0 || e.StatusCode >= http.StatusInternalServerError
+}
+
+// HTTPManifest represents the manifest served by a prompt service, listing the paths of the prompt files relative to
+// the base URL.
+type HTTPManifest struct {
+	Files []string `json:"files"`
+}
+
+// HTTPStoreOption configures optional behaviour of an HTTPStore.
+type HTTPStoreOption func(s *HTTPStore)
+
+// WithBearerToken sets a bearer token which is sent in the Authorization header of each request.
+func WithBearerToken(token string) HTTPStoreOption {
+	return func(s *HTTPStore) {
+		s.headers.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithHTTPHeader sets a header which is sent with each request.
+func WithHTTPHeader(key string, value string) HTTPStoreOption {
+	return func(s *HTTPStore) {
+		s.headers.Set(key, value)
+	}
+}
+
+// WithHTTPTimeout sets the timeout for each request, which defaults to 30 seconds.
+func WithHTTPTimeout(timeout time.Duration) HTTPStoreOption {
+	return func(s *HTTPStore) {
+		s.timeout = timeout
+	}
+}
+
+// WithHTTPMaxBodySize sets the maximum size, in bytes, of each response body, which defaults to 10 MiB. Larger
+// responses are rejected with an HTTPStoreError.
+func WithHTTPMaxBodySize(size int64) HTTPStoreOption {
+	return func(s *HTTPStore) {
+		s.maxBodySize = size
+	}
+}
+
+// WithHTTPLoaderOptions sets the options used to load the prompt files, such as WithNamespaces or WithInclude. The
+// paths listed in the manifest are matched in the same way as the paths of an FSStore, and the options are also used
+// to load a bundle when using WithHTTPBundle.
+func WithHTTPLoaderOptions(options ...LoaderOption) HTTPStoreOption {
+	return func(s *HTTPStore) {
+		s.loaderOptions = append(s.loaderOptions, options...)
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to make requests, for example to configure a proxy or TLS settings.
+func WithHTTPClient(client *http.Client) HTTPStoreOption {
+	return func(s *HTTPStore) {
+		s.client = client
+	}
+}
+
+// WithFallbackCache sets a directory where fetched prompt files are cached on disk. When the prompt service cannot be
+// reached, or responds with a server error, the prompt files are loaded from the cache instead.
+func WithFallbackCache(dir string) HTTPStoreOption {
+	return func(s *HTTPStore) {
+		s.cacheDir = strings.TrimSpace(dir)
+	}
+}
+
+// WithHTTPBundle fetches the prompt files as a single zip or tar.gz bundle at the path relative to the base URL, such
+// as one written by WriteBundle, instead of fetching a manifest and each prompt file separately. The bundle is loaded
+// in the same way as an ArchiveStore created with the options.
+func WithHTTPBundle(bundlePath string, options ...ArchiveStoreOption) HTTPStoreOption {
+	return func(s *HTTPStore) {
+		s.bundle = strings.TrimSpace(bundlePath)
+		s.archiveOptions = options
+	}
+}
+
+// httpResponse represents the body of a previously fetched file and the ETag it was served with.
+type httpResponse struct {
+	ETag string
+	Body []byte
+}
+
+// HTTPStore represents a storage system which fetches prompt files from a central prompt service. The service serves
+// a manifest at `manifest.json` under the base URL, listing the prompt files to fetch relative to the same URL, or a
+// bundle of the prompt files when using WithHTTPBundle. Responses are fetched conditionally using their ETag, so
+// unchanged files are not downloaded again. Prompt files are named from their file name without the extension, and
+// loading fails if two prompt files have the same name.
+type HTTPStore struct {
+	baseURL        *url.URL
+	client         *http.Client
+	timeout        time.Duration
+	maxBodySize    int64
+	headers        http.Header
+	cacheDir       string
+	bundle         string
+	archiveOptions []ArchiveStoreOption
+	loaderOptions  []LoaderOption
+	mu             sync.Mutex
+	responses      map[string]httpResponse
+	loadErrors     []LoadError
+}
+
+// NewHTTPStore creates a new HTTPStore which fetches prompt files from the specified base URL.
+func NewHTTPStore(baseURL string, options ...HTTPStoreOption) (*HTTPStore, error) {
+	trimmedURL := strings.TrimSpace(baseURL)
+	if trimmedURL == "" {
+		return nil, &HTTPStoreError{
+			Message: "The specified base URL is empty",
+		}
+	}
+
+	parsedURL, err := url.Parse(trimmedURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return nil, &HTTPStoreError{
+			Message: "The specified base URL is not a valid http or https URL",
+			Err:     err,
+		}
+	}
+
+	if !strings.HasSuffix(parsedURL.Path, "/") {
+		parsedURL.Path += "/"
+	}
+
+	store := &HTTPStore{
+		baseURL:     parsedURL,
+		client:      http.DefaultClient,
+		timeout:     defaultHTTPTimeout,
+		maxBodySize: defaultHTTPMaxBodySize,
+		headers:     make(http.Header),
+		responses:   make(map[string]httpResponse),
+	}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	if store.maxBodySize < 1 {
+		return nil, &HTTPStoreError{
+			Message: fmt.Sprintf("invalid maximum body size: %d", store.maxBodySize),
+		}
+	}
+
+	if store.bundle != "" && (!fs.ValidPath(store.bundle) || store.bundle == manifestETagFileName) {
+		return nil, &HTTPStoreError{
+			Message: fmt.Sprintf("invalid bundle path: %s", store.bundle),
+		}
+	}
+
+	// Prime the previously fetched responses from the fallback cache, if there is one, so that the first load after a
+	// restart can be made conditionally
+	if store.cacheDir != "" && store.bundle != "" {
+		_, _ = store.readBundleCache()
+	} else if store.cacheDir != "" {
+		_, _, _ = store.readCache()
+	}
+
+	return store, nil
+}
+
+// Load fetches the manifest and all prompt files listed in it from the prompt service, and returns a slice of
+// PromptFile objects. If the service cannot be reached and a fallback cache has been configured, the prompt files
+// from the last successful load are returned instead.
+func (s *HTTPStore) Load() ([]PromptFile, error) {
+	return s.LoadContext(context.Background())
+}
+
+// LoadContext fetches the prompt files from the prompt service in the same way as Load, cancelling the requests if the
+// context is done. The fallback cache is not used when the context is done.
+func (s *HTTPStore) LoadContext(ctx context.Context) ([]PromptFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var promptFiles []PromptFile
+	var err error
+
+	if s.bundle != "" {
+		promptFiles, err = s.loadBundle(ctx)
+	} else {
+		promptFiles, err = s.loadManifest(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(promptFiles))
+	for _, promptFile := range promptFiles {
+		if names[promptFile.Name] {
+			return nil, &HTTPStoreError{
+				Message: fmt.Sprintf("duplicate prompt file name: %s", promptFile.Name),
+			}
+		}
+		names[promptFile.Name] = true
+	}
+
+	return promptFiles, nil
+}
+
+// Errors returns the errors for the files skipped by the most recent call to Load, when the store was created with
+// WithLenient in its loader options.
+func (s *HTTPStore) Errors() []LoadError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.loadErrors)
+}
+
+// loadManifest fetches the manifest and the files it lists from the prompt service, falling back to the cache if the
+// service cannot be reached, and loads the prompt files using the loader options.
+func (s *HTTPStore) loadManifest(ctx context.Context) ([]PromptFile, error) {
+	manifest, files, err := s.fetchAll(ctx)
+	if err != nil {
+		var storeErr *HTTPStoreError
+		if s.cacheDir == "" || ctx.Err() != nil || !errors.As(err, &storeErr) || !storeErr.unreachable() {
+			return nil, err
+		}
+
+		var cacheErr error
+		if manifest, files, cacheErr = s.readCache(); cacheErr != nil {
+			return nil, err
+		}
+	} else if s.cacheDir != "" {
+		if err = s.writeCache(files); err != nil {
+			return nil, err
+		}
+	}
+
+	options := newLoaderOptions(s.loaderOptions)
+	walker, err := newPromptWalker(nil, "", options, func(filePath string) (*PromptFile, error) {
+		source := SourceMetadata{Path: s.baseURL.JoinPath(filePath).String(), Loader: HTTPSource}
+		return newPromptFileFromSource(promptFileNameFromPath(filePath), files[filePath], source, options.promptFile...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	promptFiles, err := walker.walkPaths(ctx, manifest.Files)
+	s.loadErrors = walker.loadErrors
+
+	return promptFiles, err
+}
+
+// loadBundle fetches the bundle from the prompt service, falling back to the cached bundle in the same way as
+// loadManifest, and loads the prompt files it contains.
+func (s *HTTPStore) loadBundle(ctx context.Context) ([]PromptFile, error) {
+	content, err := s.fetch(ctx, s.bundle)
+	if err != nil {
+		var storeErr *HTTPStoreError
+		if s.cacheDir == "" || ctx.Err() != nil || !errors.As(err, &storeErr) || !storeErr.unreachable() {
+			return nil, err
+		}
+
+		var cacheErr error
+		if content, cacheErr = s.readBundleCache(); cacheErr != nil {
+			return nil, err
+		}
+	} else if s.cacheDir != "" {
+		if err = s.writeBundleCache(content); err != nil {
+			return nil, err
+		}
+	}
+
+	archiveOptions := append(slices.Clone(s.archiveOptions), WithArchiveLoaderOptions(s.loaderOptions...))
+	archive, err := NewArchiveStoreFromBytes(content, archiveOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	promptFiles, err := archive.LoadContext(ctx)
+	s.loadErrors = archive.Errors()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range promptFiles {
+		promptFiles[i].Source.Loader = HTTPSource
+	}
+
+	return promptFiles, nil
+}
+
+// fetchAll fetches the manifest and every file it lists, returning the file contents keyed by path.
+func (s *HTTPStore) fetchAll(ctx context.Context) (HTTPManifest, map[string][]byte, error) {
+	var manifest HTTPManifest
+
+	content, err := s.fetch(ctx, manifestFileName)
+	if err != nil {
+		return manifest, nil, err
+	}
+
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return manifest, nil, &HTTPStoreError{
+			Message: fmt.Sprintf("failed to parse manifest: %v", err),
+			Err:     err,
+		}
+	}
+
+	files := make(map[string][]byte, len(manifest.Files))
+	for _, filePath := range manifest.Files {
+		if !fs.ValidPath(filePath) || filePath == manifestFileName || filePath == manifestETagFileName {
+			return manifest, nil, &HTTPStoreError{
+				Message: fmt.Sprintf("invalid path in manifest: %s", filePath),
+			}
+		}
+
+		if files[filePath], err = s.fetch(ctx, filePath); err != nil {
+			return manifest, nil, err
+		}
+	}
+
+	return manifest, files, nil
+}
+
+// fetch retrieves a file relative to the base URL. If the file has been fetched before then the request is made
+// conditionally, and the previously fetched body is returned if the server reports it has not been modified.
+func (s *HTTPStore) fetch(ctx context.Context, filePath string) ([]byte, error) {
+	fileURL := s.baseURL.JoinPath(filePath)
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL.String(), nil)
+	if err != nil {
+		return nil, &HTTPStoreError{
+			Message: fmt.Sprintf("failed to create request for %s: %v", filePath, err),
+			Err:     err,
+		}
+	}
+
+	for key, values := range s.headers {
+		request.Header[key] = values
+	}
+
+	previous, hasPrevious := s.responses[filePath]
+	if hasPrevious && len(previous.ETag) > 0 {
+		request.Header.Set("If-None-Match", previous.ETag)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, &HTTPStoreError{
+			Message: fmt.Sprintf("failed to fetch %s: %v", filePath, err),
+			Err:     err,
+		}
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotModified && hasPrevious:
+		return previous.Body, nil
+	case response.StatusCode != http.StatusOK:
+		return nil, &HTTPStoreError{
+			Message:    fmt.Sprintf("failed to fetch %s: %s", filePath, response.Status),
+			StatusCode: response.StatusCode,
+		}
+	}
+
+	if response.ContentLength > s.maxBodySize {
+		return nil, &HTTPStoreError{
+			Message:    fmt.Sprintf("%s exceeds the maximum size of %d bytes", filePath, s.maxBodySize),
+			StatusCode: response.StatusCode,
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, s.maxBodySize+1))
+	if err != nil {
+		return nil, &HTTPStoreError{
+			Message: fmt.Sprintf("failed to read %s: %v", filePath, err),
+			Err:     err,
+		}
+	}
+
+	if int64(len(body)) > s.maxBodySize {
+		return nil, &HTTPStoreError{
+			Message:    fmt.Sprintf("%s exceeds the maximum size of %d bytes", filePath, s.maxBodySize),
+			StatusCode: response.StatusCode,
+		}
+	}
+
+	s.responses[filePath] = httpResponse{ETag: response.Header.Get("ETag"), Body: body}
+
+	return body, nil
+}
+
+// writeCache writes the manifest, the fetched files, and their ETags to the fallback cache directory.
+func (s *HTTPStore) writeCache(files map[string][]byte) error {
+	etags := make(map[string]string, len(files)+1)
+	for filePath, content := range files {
+		if err := writeCacheFile(s.cacheDir, filePath, content); err != nil {
+			return err
+		}
+		etags[filePath] = s.responses[filePath].ETag
+	}
+	etags[manifestFileName] = s.responses[manifestFileName].ETag
+
+	etagContent, err := json.Marshal(etags)
+	if err != nil {
+		return &HTTPStoreError{
+			Message: fmt.Sprintf("failed to write fallback cache: %v", err),
+			Err:     err,
+		}
+	}
+
+	if err = writeCacheFile(s.cacheDir, manifestETagFileName, etagContent); err != nil {
+		return err
+	}
+
+	// The manifest is written last so that an interrupted write never leaves a manifest referring to missing files
+	return writeCacheFile(s.cacheDir, manifestFileName, s.responses[manifestFileName].Body)
+}
+
+// readCache reads the manifest and files from the fallback cache directory, and primes the ETags of the cached files
+// so that they can be fetched conditionally.
+func (s *HTTPStore) readCache() (HTTPManifest, map[string][]byte, error) {
+	var manifest HTTPManifest
+
+	manifestContent, err := os.ReadFile(filepath.Join(s.cacheDir, manifestFileName))
+	if err != nil {
+		return manifest, nil, err
+	}
+
+	if err = json.Unmarshal(manifestContent, &manifest); err != nil {
+		return manifest, nil, err
+	}
+
+	etags := make(map[string]string)
+	if etagContent, etagErr := os.ReadFile(filepath.Join(s.cacheDir, manifestETagFileName)); etagErr == nil {
+		_ = json.Unmarshal(etagContent, &etags)
+	}
+
+	s.responses[manifestFileName] = httpResponse{ETag: etags[manifestFileName], Body: manifestContent}
+
+	files := make(map[string][]byte, len(manifest.Files))
+	for _, filePath := range manifest.Files {
+		if !fs.ValidPath(filePath) {
+			return manifest, nil, fmt.Errorf("invalid path in cached manifest: %s", filePath)
+		}
+
+		content, readErr := os.ReadFile(filepath.Join(s.cacheDir, filepath.FromSlash(filePath)))
+		if readErr != nil {
+			return manifest, nil, readErr
+		}
+		files[filePath] = content
+		s.responses[filePath] = httpResponse{ETag: etags[filePath], Body: content}
+	}
+
+	return manifest, files, nil
+}
+
+// writeBundleCache writes the bundle and its ETag to the fallback cache directory.
+func (s *HTTPStore) writeBundleCache(content []byte) error {
+	etagContent, err := json.Marshal(map[string]string{s.bundle: s.responses[s.bundle].ETag})
+	if err != nil {
+		return &HTTPStoreError{
+			Message: fmt.Sprintf("failed to write fallback cache: %v", err),
+			Err:     err,
+		}
+	}
+
+	if err = writeCacheFile(s.cacheDir, s.bundle, content); err != nil {
+		return err
+	}
+
+	return writeCacheFile(s.cacheDir, manifestETagFileName, etagContent)
+}
+
+// readBundleCache reads the bundle from the fallback cache directory, and primes its ETag so that it can be fetched
+// conditionally.
+func (s *HTTPStore) readBundleCache() ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(s.cacheDir, filepath.FromSlash(s.bundle)))
+	if err != nil {
+		return nil, err
+	}
+
+	etags := make(map[string]string)
+	if etagContent, etagErr := os.ReadFile(filepath.Join(s.cacheDir, manifestETagFileName)); etagErr == nil {
+		_ = json.Unmarshal(etagContent, &etags)
+	}
+
+	s.responses[s.bundle] = httpResponse{ETag: etags[s.bundle], Body: content}
+
+	return content, nil
+}
+
+// writeCacheFile writes a single file to the cache directory, creating any parent directories.
+func writeCacheFile(cacheDir string, filePath string, content []byte) error {
+	target := filepath.Join(cacheDir, filepath.FromSlash(path.Clean(filePath)))
+
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return &HTTPStoreError{
+			Message: fmt.Sprintf("failed to write fallback cache: %v", err),
+			Err:     err,
+		}
+	}
+
+	if err := os.WriteFile(target, content, 0600); err != nil {
+		return &HTTPStoreError{
+			Message: fmt.Sprintf("failed to write fallback cache: %v", err),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,561 @@
+package dotprompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// promptServer is a test prompt service which serves the manifest and prompt files from the file-store-tests
+// directory, supporting ETags and recording the requests it receives.
+type promptServer struct {
+	mu           sync.Mutex
+	manifest     string
+	token        string
+	status       int
+	delay        time.Duration
+	notModified  int
+	lastHeaders  http.Header
+	fileContents map[string]string
+}
+
+func newPromptServer(t *testing.T) *promptServer {
+	t.Helper()
+
+	fileContents := make(map[string]string)
+	for _, name := range []string{"basic.prompt", "sub-dir/another-with-name.prompt"} {
+		content, err := os.ReadFile(path.Join("file-store-tests", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileContents[name] = string(content)
+	}
+
+	return &promptServer{
+		manifest:     `{"files": ["basic.prompt", "sub-dir/another-with-name.prompt", "README.md"]}`,
+		fileContents: fileContents,
+	}
+}
+
+func (p *promptServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	time.Sleep(p.delay)
+	p.lastHeaders = r.Header.Clone()
+
+	if p.status != 0 {
+		w.WriteHeader(p.status)
+		return
+	}
+
+	if p.token != "" && r.Header.Get("Authorization") != "Bearer "+p.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	filePath := strings.TrimPrefix(r.URL.Path, "/prompts/")
+	var content string
+	switch filePath {
+	case "manifest.json":
+		content = p.manifest
+	case "README.md":
+		content = "# Prompts"
+	default:
+		var ok bool
+		if content, ok = p.fileContents[filePath]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+
+	etag := fmt.Sprintf(`"%d"`, len(content))
+	if r.Header.Get("If-None-Match") == etag {
+		p.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	_, _ = w.Write([]byte(content))
+}
+
+func promptFileNames(promptFiles []PromptFile) []string {
+	names := make([]string, 0, len(promptFiles))
+	for _, promptFile := range promptFiles {
+		names = append(names, promptFile.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestHTTPStore_Load(t *testing.T) {
+	server := newPromptServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL + "/prompts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"another-example-with-name", "basic"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+}
+
+func TestHTTPStore_Load_UsesConditionalRequests(t *testing.T) {
+	server := newPromptServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL + "/prompts/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 2 {
+		t.Errorf("Expected 2 prompt files, got %d", len(promptFiles))
+	}
+
+	if server.notModified != 4 {
+		t.Errorf("Expected 4 not modified responses, got %d", server.notModified)
+	}
+}
+
+func TestHTTPStore_Load_WithAuthentication(t *testing.T) {
+	server := newPromptServer(t)
+	server.token = "secret"
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL+"/prompts", WithBearerToken("secret"), WithHTTPHeader("X-Team", "platform"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.lastHeaders.Get("X-Team") != "platform" {
+		t.Errorf("Expected X-Team header to be 'platform', got '%s'", server.lastHeaders.Get("X-Team"))
+	}
+
+	unauthorized, err := NewHTTPStore(httpServer.URL + "/prompts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = unauthorized.Load()
+
+	var storeError *HTTPStoreError
+	if !errors.As(err, &storeError) {
+		t.Fatalf("Expected HTTPStoreError, got %T", err)
+	}
+
+	if storeError.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code 401, got %d", storeError.StatusCode)
+	}
+}
+
+func TestHTTPStore_Load_WithHTTPLoaderOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []LoaderOption
+		expected []string
+	}{
+		{"namespaces", []LoaderOption{WithNamespaces()}, []string{"basic", "sub-dir/another-example-with-name"}},
+		{"exclude", []LoaderOption{WithExclude("sub-dir/")}, []string{"basic"}},
+		{"include", []LoaderOption{WithInclude("/sub-dir/**")}, []string{"another-example-with-name"}},
+		{"max-depth", []LoaderOption{WithMaxDepth(0)}, []string{"basic"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			httpServer := httptest.NewServer(newPromptServer(t))
+			defer httpServer.Close()
+
+			store, err := NewHTTPStore(httpServer.URL+"/prompts", WithHTTPLoaderOptions(test.options...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			promptFiles, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if names := promptFileNames(promptFiles); !slices.Equal(names, test.expected) {
+				t.Errorf("Expected prompt files %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestHTTPStore_Load_WithDuplicateNames(t *testing.T) {
+	server := newPromptServer(t)
+	server.manifest = `{"files": ["basic.prompt", "other/basic.prompt"]}`
+	server.fileContents["other/basic.prompt"] = server.fileContents["basic.prompt"]
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL + "/prompts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Load()
+
+	var storeError *HTTPStoreError
+	if !errors.As(err, &storeError) {
+		t.Fatalf("Expected HTTPStoreError, got %T", err)
+	}
+
+	expectedError := "duplicate prompt file name: basic"
+	if storeError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, storeError.Error())
+	}
+
+	// Namespaces qualify the names so that they no longer collide
+	namespaced, err := NewHTTPStore(httpServer.URL+"/prompts", WithHTTPLoaderOptions(WithNamespaces()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := namespaced.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"basic", "other/basic"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+}
+
+func TestHTTPStore_Load_WithHTTPMaxBodySize(t *testing.T) {
+	server := newPromptServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL+"/prompts", WithHTTPMaxBodySize(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Load()
+
+	var storeError *HTTPStoreError
+	if !errors.As(err, &storeError) {
+		t.Fatalf("Expected HTTPStoreError, got %T", err)
+	}
+
+	expectedError := "manifest.json exceeds the maximum size of 16 bytes"
+	if storeError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, storeError.Error())
+	}
+
+	// Responses without a content length are limited while they are read
+	chunkedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(strings.Repeat(" ", 64)))
+	}))
+	defer chunkedServer.Close()
+
+	chunkedStore, err := NewHTTPStore(chunkedServer.URL+"/prompts", WithHTTPMaxBodySize(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = chunkedStore.Load()
+	if !errors.As(err, &storeError) {
+		t.Fatalf("Expected HTTPStoreError, got %T", err)
+	}
+
+	if storeError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, storeError.Error())
+	}
+}
+
+func TestHTTPStore_Load_WithHTTPTimeout(t *testing.T) {
+	server := newPromptServer(t)
+	server.delay = 200 * time.Millisecond
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL+"/prompts", WithHTTPTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Load()
+
+	var storeError *HTTPStoreError
+	if !errors.As(err, &storeError) {
+		t.Fatalf("Expected HTTPStoreError, got %T", err)
+	}
+}
+
+func TestHTTPStore_Load_WithFallbackCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	server := newPromptServer(t)
+	httpServer := httptest.NewServer(server)
+
+	store, err := NewHTTPStore(httpServer.URL+"/prompts", WithFallbackCache(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Server errors fall back to the cache
+	server.status = http.StatusServiceUnavailable
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 2 {
+		t.Errorf("Expected 2 prompt files from the cache, got %d", len(promptFiles))
+	}
+
+	// A new store can load from the cache when the server cannot be reached at all
+	httpServer.Close()
+
+	offlineStore, err := NewHTTPStore(httpServer.URL+"/prompts", WithFallbackCache(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err = offlineStore.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"another-example-with-name", "basic"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+}
+
+func TestHTTPStore_Load_WithHTTPBundle(t *testing.T) {
+	cacheDir := t.TempDir()
+	server := newPromptServer(t)
+	server.fileContents["prompts.zip"] = string(newTestBundle(t, ZipArchive))
+	httpServer := httptest.NewServer(server)
+
+	store, err := NewHTTPStore(httpServer.URL+"/prompts", WithHTTPBundle("prompts.zip", WithRequiredManifest()), WithFallbackCache(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"another-example-with-name", "basic"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+
+	if promptFiles[0].Source.Loader != HTTPSource {
+		t.Errorf("Expected loader '%s', got '%s'", HTTPSource, promptFiles[0].Source.Loader)
+	}
+
+	// The bundle is fetched conditionally on the next load
+	if _, err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.notModified != 1 {
+		t.Errorf("Expected 1 not modified response, got %d", server.notModified)
+	}
+
+	// A new store can load the cached bundle when the server cannot be reached
+	httpServer.Close()
+
+	offlineStore, err := NewHTTPStore(httpServer.URL+"/prompts", WithHTTPBundle("prompts.zip"), WithFallbackCache(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err = offlineStore.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+}
+
+func TestHTTPStore_Load_WithFallbackCache_PrimesConditionalRequests(t *testing.T) {
+	cacheDir := t.TempDir()
+	server := newPromptServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL+"/prompts", WithFallbackCache(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	restartedStore, err := NewHTTPStore(httpServer.URL+"/prompts", WithFallbackCache(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = restartedStore.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.notModified != 4 {
+		t.Errorf("Expected 4 not modified responses, got %d", server.notModified)
+	}
+}
+
+func TestHTTPStore_LoadContext_WithCancelledContext_DoesNotFallBack(t *testing.T) {
+	cacheDir := t.TempDir()
+	server := newPromptServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL+"/prompts", WithFallbackCache(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err = store.LoadContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestHTTPStore_Load_WithClientError_DoesNotFallBack(t *testing.T) {
+	cacheDir := t.TempDir()
+	server := newPromptServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	store, err := NewHTTPStore(httpServer.URL+"/prompts", WithFallbackCache(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	server.status = http.StatusForbidden
+	if _, err = store.Load(); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+}
+
+func TestHTTPStore_Load_WithInvalidManifest(t *testing.T) {
+	tests := []struct {
+		name          string
+		manifest      string
+		expectedError string
+	}{
+		{"invalid-json", `{`, "failed to parse manifest"},
+		{"escaping-path", `{"files": ["../secret.prompt"]}`, "invalid path in manifest: ../secret.prompt"},
+		{"missing-file", `{"files": ["missing.prompt"]}`, "failed to fetch missing.prompt: 404 Not Found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := newPromptServer(t)
+			server.manifest = test.manifest
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			store, err := NewHTTPStore(httpServer.URL + "/prompts")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = store.Load()
+
+			var storeError *HTTPStoreError
+			if !errors.As(err, &storeError) {
+				t.Fatalf("Expected HTTPStoreError, got %T", err)
+			}
+
+			if !strings.HasPrefix(storeError.Error(), test.expectedError) {
+				t.Errorf("Expected error to start with '%s', got '%s'", test.expectedError, storeError.Error())
+			}
+		})
+	}
+}
+
+func TestNewHTTPStore_WithInvalidArguments(t *testing.T) {
+	tests := []struct {
+		name          string
+		baseURL       string
+		options       []HTTPStoreOption
+		expectedError string
+	}{
+		{"empty-url", " ", nil, "The specified base URL is empty"},
+		{"invalid-scheme", "ftp://example.com/prompts", nil, "The specified base URL is not a valid http or https URL"},
+		{"invalid-url", "http://[::1", nil, "The specified base URL is not a valid http or https URL"},
+		{"invalid-max-body-size", "http://example.com/prompts", []HTTPStoreOption{WithHTTPMaxBodySize(0)}, "invalid maximum body size: 0"},
+		{"invalid-bundle", "http://example.com/prompts", []HTTPStoreOption{WithHTTPBundle("../prompts.zip")}, "invalid bundle path: ../prompts.zip"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewHTTPStore(test.baseURL, test.options...)
+
+			var storeError *HTTPStoreError
+			if !errors.As(err, &storeError) {
+				t.Fatalf("Expected HTTPStoreError, got %T", err)
+			}
+
+			if storeError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, storeError.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package dotprompt
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// validateJSONSchema validates a JSON decoded value against a JSON Schema. Only the subset of JSON Schema commonly
+// used to describe tool parameters is supported: type, enum, properties, required, additionalProperties, and items.
+// The path identifies the location of the value and is used in error messages.
+func validateJSONSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if schemaType, ok := schema["type"]; ok {
+		if err := validateJSONSchemaType(schemaType, value, path); err != nil {
+			return err
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		if !slices.ContainsFunc(enum, func(item interface{}) bool { return jsonValuesEqual(item, value) }) {
+			return fmt.Errorf("%s: value %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		return validateJSONSchemaObject(schema, typedValue, path)
+	case []interface{}:
+		itemSchema, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range typedValue {
+			if err := validateJSONSchema(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateJSONSchemaObject validates the properties of an object against the properties, required, and
+// additionalProperties keywords of the schema.
+func validateJSONSchemaObject(schema map[string]interface{}, value map[string]interface{}, path string) error {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, found := value[fmt.Sprint(name)]; !found {
+				return fmt.Errorf("%s: missing required property %v", path, name)
+			}
+		}
+	}
+
+	// Iterate over the keys in order so that the reported error is deterministic
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propertyPath := path + "." + key
+		if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+			if err := validateJSONSchema(propertySchema, value[key], propertyPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: additional property is not allowed", propertyPath)
+			}
+		case map[string]interface{}:
+			if err := validateJSONSchema(additional, value[key], propertyPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateJSONSchemaType checks that the value matches the schema type, which may be a single type name or a list
+// of type names.
+func validateJSONSchemaType(schemaType interface{}, value interface{}, path string) error {
+	var types []string
+	switch typed := schemaType.(type) {
+	case string:
+		types = []string{typed}
+	case []interface{}:
+		for _, item := range typed {
+			types = append(types, fmt.Sprint(item))
+		}
+	default:
+		return fmt.Errorf("%s: invalid schema type %v", path, schemaType)
+	}
+
+	for _, expected := range types {
+		if jsonValueHasType(value, expected) {
+			return nil
+		}
+	}
+
+	if len(types) == 1 {
+		return fmt.Errorf("%s: expected %s", path, types[0])
+	}
+	return fmt.Errorf("%s: expected one of %v", path, types)
+}
+
+// jsonValueHasType returns true if the JSON decoded value is of the named JSON Schema type.
+func jsonValueHasType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	}
+	return false
+}
+
+// jsonValuesEqual compares two values, treating numbers of different Go types as equal when their values match.
+// This allows enum values read from YAML, which may be ints, to be compared with values decoded from JSON.
+func jsonValuesEqual(a interface{}, b interface{}) bool {
+	if isNumeric(a) && isNumeric(b) {
+		return reflect.ValueOf(a).Convert(reflect.TypeOf(float64(0))).Float() ==
+			reflect.ValueOf(b).Convert(reflect.TypeOf(float64(0))).Float()
+	}
+	return reflect.DeepEqual(a, b)
+}
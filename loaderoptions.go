@@ -0,0 +1,585 @@
+package dotprompt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// promptIgnoreFileName is the name of the file, at the root of a store, which lists patterns of files and directories
+// to exclude when loading prompt files.
+const promptIgnoreFileName string = ".promptignore"
+
+// SymlinkMode determines how symbolic links are handled when loading prompt files from a directory.
+type SymlinkMode int
+
+const (
+	// SymlinkFiles loads prompt files which are symbolic links, but does not walk into linked directories. This is
+	// the default.
+	SymlinkFiles SymlinkMode = iota
+
+	// SymlinkFollow loads prompt files which are symbolic links and walks into linked directories, skipping any link
+	// which would create a cycle.
+	SymlinkFollow
+
+	// SymlinkSkip ignores all symbolic links.
+	SymlinkSkip
+)
+
+// LoaderOption configures which files are loaded by a FileStore, FSStore, or HTTPStore, and how invalid files are
+// handled.
+type LoaderOption func(o *loaderOptions)
+
+// loaderOptions holds the options used when walking a directory for prompt files.
+type loaderOptions struct {
+	include     []string
+	exclude     []string
+	maxDepth    int
+	symlinks    SymlinkMode
+	lenient     bool
+	namespaces  bool
+	concurrency int
+	promptFile  []PromptFileOption
+}
+
+// newLoaderOptions returns the loader options with the provided options applied to the defaults.
+func newLoaderOptions(options []LoaderOption) loaderOptions {
+	opts := loaderOptions{
+		maxDepth:    -1,
+		symlinks:    SymlinkFiles,
+		concurrency: 1,
+	}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	if opts.concurrency < 1 {
+		opts.concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	return opts
+}
+
+// WithInclude restricts loading to prompt files matching at least one of the glob patterns. Patterns are matched
+// against the slash separated path relative to the root of the store. A pattern without a slash matches the file name
+// at any depth, a leading slash anchors the pattern to the root, and "**" matches any number of directories.
+func WithInclude(patterns ...string) LoaderOption {
+	return func(o *loaderOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithExclude skips files and directories matching any of the glob patterns, using the same syntax as the lines of a
+// .promptignore file.
+func WithExclude(patterns ...string) LoaderOption {
+	return func(o *loaderOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithMaxDepth limits how many levels of subdirectories are walked, where 0 loads only the prompt files in the root
+// directory. A negative depth, which is the default, walks every subdirectory.
+func WithMaxDepth(depth int) LoaderOption {
+	return func(o *loaderOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// WithSymlinks sets how symbolic links are handled, which defaults to SymlinkFiles.
+func WithSymlinks(mode SymlinkMode) LoaderOption {
+	return func(o *loaderOptions) {
+		o.symlinks = mode
+	}
+}
+
+// WithLenient skips prompt files which fail to load instead of failing the whole load. The errors for the skipped
+// files are available from the Errors method of the store after loading.
+func WithLenient() LoaderOption {
+	return func(o *loaderOptions) {
+		o.lenient = true
+	}
+}
+
+// WithNamespaces qualifies the name of each prompt file with the directory it was loaded from, relative to the root
+// of the store, so that "support/summarise.prompt" is named "support/summarise". A name given in the front matter of
+// the prompt file replaces the file name, but the prompt file remains within the namespace of its directory. An FSStore
+// names prompt files without their extension when namespaces are used, in the same way as a FileStore.
+func WithNamespaces() LoaderOption {
+	return func(o *loaderOptions) {
+		o.namespaces = true
+	}
+}
+
+// WithConcurrency sets the number of prompt files which are parsed at the same time, where a concurrency below 1 uses
+// GOMAXPROCS. By default, prompt files are loaded sequentially. The loaded prompt files, and any errors, are returned in
+// the same order regardless of the concurrency.
+func WithConcurrency(workers int) LoaderOption {
+	return func(o *loaderOptions) {
+		o.concurrency = workers
+	}
+}
+
+// WithPromptFileOptions sets the options used to create each prompt file, such as WithStrict to reject prompt files
+// containing unknown fields.
+func WithPromptFileOptions(options ...PromptFileOption) LoaderOption {
+	return func(o *loaderOptions) {
+		o.promptFile = append(o.promptFile, options...)
+	}
+}
+
+// LoadError represents an error loading a single file, which was skipped because the store was created with
+// WithLenient.
+type LoadError struct {
+	Path string
+	Err  error
+}
+
+// Error returns the path of the file and the error encountered loading it.
+func (e LoadError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e LoadError) Unwrap() error {
+	return e.Err
+}
+
+// globPattern represents a parsed exclude pattern.
+type globPattern struct {
+	pattern  string
+	negated  bool
+	dirOnly  bool
+	anchored bool
+}
+
+// parseGlobPattern parses a pattern using the .promptignore syntax, where a leading "!" re-includes a previously
+// excluded path and a trailing slash matches only directories.
+func parseGlobPattern(pattern string) (globPattern, error) {
+	parsed := globPattern{}
+
+	if strings.HasPrefix(pattern, "!") {
+		parsed.negated = true
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		parsed.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	if strings.HasPrefix(pattern, "/") {
+		parsed.anchored = true
+		pattern = strings.TrimLeft(pattern, "/")
+	} else if strings.Contains(pattern, "/") {
+		parsed.anchored = true
+	}
+
+	if pattern == "" {
+		return parsed, fmt.Errorf("empty pattern")
+	}
+
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return parsed, err
+		}
+	}
+
+	parsed.pattern = pattern
+
+	return parsed, nil
+}
+
+// matches returns true if the pattern matches the slash separated path.
+func (g globPattern) matches(name string, isDir bool) bool {
+	if g.dirOnly && !isDir {
+		return false
+	}
+
+	if !g.anchored {
+		return matchGlobSegments([]string{g.pattern}, []string{path.Base(name)})
+	}
+
+	return matchGlobSegments(strings.Split(g.pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchGlobSegments matches the segments of a path against the segments of a pattern, where a "**" segment matches
+// zero or more path segments.
+func matchGlobSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// promptWalker walks a file system for prompt files, applying the loader options.
+type promptWalker struct {
+	fsys       fs.FS
+	root       string
+	options    loaderOptions
+	include    []globPattern
+	exclude    []globPattern
+	load       func(filePath string) (*PromptFile, error)
+	loadErrors []LoadError
+}
+
+// newPromptWalker creates a walker over the file system, reading the .promptignore file at its root if there is one.
+// The root is the location of the file system, used to report the paths of files which fail to load, and the load
+// function creates a PromptFile from a slash separated path relative to the root. The file system is nil for stores
+// which list their files, which have no .promptignore file.
+func newPromptWalker(fsys fs.FS, root string, options loaderOptions, load func(filePath string) (*PromptFile, error)) (*promptWalker, error) {
+	walker := &promptWalker{
+		fsys:    fsys,
+		root:    root,
+		options: options,
+		load:    load,
+	}
+
+	for _, pattern := range options.include {
+		parsed, err := parseGlobPattern(pattern)
+		if err != nil || parsed.negated {
+			return nil, &FileStoreError{
+				Message: fmt.Sprintf("invalid include pattern: %s", pattern),
+				Err:     err,
+			}
+		}
+		walker.include = append(walker.include, parsed)
+	}
+
+	patterns := options.exclude
+	if fsys != nil {
+		ignoreContent, err := fs.ReadFile(fsys, promptIgnoreFileName)
+		if err == nil {
+			patterns = append(readIgnorePatterns(ignoreContent), patterns...)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, &FileStoreError{
+				Message: fmt.Sprintf("failed to read %s: %v", promptIgnoreFileName, err),
+				Err:     err,
+			}
+		}
+	}
+
+	for _, pattern := range patterns {
+		parsed, parseErr := parseGlobPattern(pattern)
+		if parseErr != nil {
+			return nil, &FileStoreError{
+				Message: fmt.Sprintf("invalid exclude pattern: %s", pattern),
+				Err:     parseErr,
+			}
+		}
+		walker.exclude = append(walker.exclude, parsed)
+	}
+
+	return walker, nil
+}
+
+// readIgnorePatterns returns the patterns in the content of a .promptignore file, skipping blank lines and comments.
+func readIgnorePatterns(content []byte) []string {
+	patterns := make([]string, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+
+	return patterns
+}
+
+// walkItem represents a prompt file found while walking, or a directory which could not be read, along with the
+// result of loading it.
+type walkItem struct {
+	path       string
+	promptFile *PromptFile
+	err        error
+}
+
+// walk loads the prompt files from the root of the file system and, depending on the options, its subdirectories.
+// The directories are walked first to find the prompt files, which are then loaded by a pool of workers. The prompt
+// files are returned in the order they were found, and when loading fails the error for the first file in that order
+// is returned, so the result is the same regardless of the number of workers.
+func (w *promptWalker) walk(ctx context.Context) ([]PromptFile, error) {
+	var ancestors []fs.FileInfo
+	if w.options.symlinks == SymlinkFollow {
+		if info, err := fs.Stat(w.fsys, "."); err == nil {
+			ancestors = append(ancestors, info)
+		}
+	}
+
+	entries, err := fs.ReadDir(w.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]walkItem, 0)
+	if err = w.collectFromDir(ctx, ".", entries, 0, ancestors, &items); err != nil {
+		return nil, err
+	}
+
+	return w.loadAll(ctx, items)
+}
+
+// walkPaths loads the prompt files at the slash separated paths, relative to the root, applying the include, exclude,
+// and depth options in the same way as walk. It is used for stores which list their files instead of walking a
+// directory.
+func (w *promptWalker) walkPaths(ctx context.Context, paths []string) ([]PromptFile, error) {
+	items := make([]walkItem, 0, len(paths))
+	for _, filePath := range paths {
+		if !isPromptFilePath(filePath) || !w.included(filePath) || w.excluded(filePath, false) {
+			continue
+		}
+
+		if w.options.maxDepth >= 0 && strings.Count(filePath, "/") > w.options.maxDepth {
+			continue
+		}
+
+		if w.dirExcluded(path.Dir(filePath)) {
+			continue
+		}
+
+		items = append(items, walkItem{path: filePath})
+	}
+
+	return w.loadAll(ctx, items)
+}
+
+// loadAll loads the prompt files for the items, returning them in order. Unless the walker is lenient, the error for
+// the first item which failed is returned.
+func (w *promptWalker) loadAll(ctx context.Context, items []walkItem) ([]PromptFile, error) {
+	if err := w.loadItems(ctx, items); err != nil {
+		return nil, err
+	}
+
+	promptFiles := make([]PromptFile, 0, len(items))
+	for _, item := range items {
+		if item.err != nil {
+			if w.options.lenient {
+				w.addError(item.path, item.err)
+				continue
+			}
+			return nil, item.err
+		}
+		promptFiles = append(promptFiles, *item.promptFile)
+	}
+
+	return promptFiles, nil
+}
+
+// collectFromDir adds the prompt files in the directory, and recursively in its subdirectories, to the items. The
+// ancestors are the directories above it, used to detect cycles when following symbolic links.
+func (w *promptWalker) collectFromDir(ctx context.Context, dirPath string, entries []fs.DirEntry, depth int, ancestors []fs.FileInfo, items *[]walkItem) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entryPath := path.Join(dirPath, entry.Name())
+		isDir := entry.IsDir()
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if w.options.symlinks == SymlinkSkip {
+				continue
+			}
+
+			info, statErr := fs.Stat(w.fsys, entryPath)
+			if statErr != nil {
+				*items = append(*items, walkItem{path: entryPath, err: statErr})
+				continue
+			}
+
+			isDir = info.IsDir()
+			if isDir && (w.options.symlinks != SymlinkFollow || isAncestor(ancestors, info)) {
+				continue
+			}
+		}
+
+		if isDir {
+			if w.excluded(entryPath, true) || (w.options.maxDepth >= 0 && depth >= w.options.maxDepth) {
+				continue
+			}
+
+			dirAncestors := ancestors
+			if w.options.symlinks == SymlinkFollow {
+				if info, statErr := fs.Stat(w.fsys, entryPath); statErr == nil {
+					dirAncestors = append(ancestors[:len(ancestors):len(ancestors)], info)
+				}
+			}
+
+			dirEntries, readErr := fs.ReadDir(w.fsys, entryPath)
+			if readErr != nil {
+				*items = append(*items, walkItem{path: entryPath, err: readErr})
+				continue
+			}
+
+			if err := w.collectFromDir(ctx, entryPath, dirEntries, depth+1, dirAncestors, items); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !isPromptFilePath(entryPath) || !w.included(entryPath) || w.excluded(entryPath, false) {
+			continue
+		}
+
+		*items = append(*items, walkItem{path: entryPath})
+	}
+
+	return nil
+}
+
+// loadItems loads the prompt files for the items using a pool of workers. Unless the walker is lenient, loading stops
+// at the first error, but every item before it is still loaded so that the first error is always found. Returns an
+// error only if the context is cancelled.
+func (w *promptWalker) loadItems(ctx context.Context, items []walkItem) error {
+	limit := len(items)
+	if !w.options.lenient {
+		for i, item := range items {
+			if item.err != nil {
+				limit = i
+				break
+			}
+		}
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+
+	for range min(w.options.concurrency, limit) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Items are taken in order, so when loading stops every item before the one which failed has been taken
+			for workerCtx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= limit {
+					return
+				}
+
+				item := &items[i]
+				if item.err != nil {
+					continue
+				}
+
+				item.promptFile, item.err = w.load(item.path)
+				if item.err != nil {
+					if !w.options.lenient {
+						cancel()
+					}
+					continue
+				}
+
+				if namespace := namespaceFromPath(item.path); w.options.namespaces && namespace != "" {
+					item.promptFile.Namespace = namespace
+					item.promptFile.Name = namespace + "/" + item.promptFile.Name
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// included returns true if there are no include patterns, or the file matches one of them.
+func (w *promptWalker) included(filePath string) bool {
+	if len(w.include) == 0 {
+		return true
+	}
+
+	for _, pattern := range w.include {
+		if pattern.matches(filePath, false) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// excluded returns true if the path is excluded by the exclude patterns, where the last matching pattern wins so that
+// a negated pattern can re-include a path excluded by an earlier one.
+func (w *promptWalker) excluded(filePath string, isDir bool) bool {
+	excluded := false
+	for _, pattern := range w.exclude {
+		if pattern.matches(filePath, isDir) {
+			excluded = !pattern.negated
+		}
+	}
+	return excluded
+}
+
+// dirExcluded returns true if the directory at the slash separated path, or any directory above it, is excluded.
+func (w *promptWalker) dirExcluded(dirPath string) bool {
+	for dirPath != "." && dirPath != "/" {
+		if w.excluded(dirPath, true) {
+			return true
+		}
+		dirPath = path.Dir(dirPath)
+	}
+	return false
+}
+
+// addError records an error loading the file at the slash separated path.
+func (w *promptWalker) addError(filePath string, err error) {
+	if w.root != "" {
+		filePath = filepath.Join(w.root, filepath.FromSlash(filePath))
+	}
+	w.loadErrors = append(w.loadErrors, LoadError{Path: filePath, Err: err})
+}
+
+// namespaceFromPath returns the namespace for a prompt file at the slash separated path, made up of its cleaned
+// directory names, or an empty string for a prompt file at the root.
+func namespaceFromPath(filePath string) string {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(path.Dir(filePath), "/") {
+		if cleaned := cleanName(segment); cleaned != "" {
+			segments = append(segments, cleaned)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isAncestor returns true if the directory is the same as one of the ancestors.
+func isAncestor(ancestors []fs.FileInfo, info fs.FileInfo) bool {
+	for _, ancestor := range ancestors {
+		if os.SameFile(ancestor, info) {
+			return true
+		}
+	}
+	return false
+}
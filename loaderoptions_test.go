@@ -0,0 +1,467 @@
+package dotprompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+const minimalPrompt = "prompts:\n  user: Hello\n"
+
+// newFilterTestFs creates a file system of nested prompt files, one of which is invalid.
+func newFilterTestFs(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{
+		"root.prompt":                     {Data: []byte("name: root\n" + minimalPrompt)},
+		"drafts/draft.prompt":             {Data: []byte("name: draft\n" + minimalPrompt)},
+		"team/support.prompt":             {Data: []byte("name: support\n" + minimalPrompt)},
+		"team/nested/escalation.prompt":   {Data: []byte("name: escalation\n" + minimalPrompt)},
+		"team/nested/broken.prompt":       {Data: []byte("name: broken\nprompts:\n  system: No user prompt\n")},
+		"team/nested/deeper/notes.prompt": {Data: []byte("name: notes\n" + minimalPrompt)},
+	}
+
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	return fsys
+}
+
+func TestFSStore_Load_WithLoaderOptions(t *testing.T) {
+	tests := []struct {
+		name          string
+		ignoreFile    string
+		options       []LoaderOption
+		expectedNames []string
+	}{
+		{
+			"max-depth-0",
+			"",
+			[]LoaderOption{WithMaxDepth(0)},
+			[]string{"root"},
+		},
+		{
+			"max-depth-1",
+			"",
+			[]LoaderOption{WithMaxDepth(1)},
+			[]string{"draft", "root", "support"},
+		},
+		{
+			"include-anchored",
+			"",
+			[]LoaderOption{WithInclude("team/*.prompt")},
+			[]string{"support"},
+		},
+		{
+			"include-recursive",
+			"",
+			[]LoaderOption{WithInclude("team/**/*.prompt"), WithExclude("broken.prompt")},
+			[]string{"escalation", "notes", "support"},
+		},
+		{
+			"exclude-directory",
+			"",
+			[]LoaderOption{WithExclude("drafts/", "nested/")},
+			[]string{"root", "support"},
+		},
+		{
+			"ignore-file",
+			"# Work in progress\ndrafts/\n\n/team/nested/*\n!/team/nested/escalation.prompt\n",
+			nil,
+			[]string{"escalation", "root", "support"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			files := make(map[string]string)
+			if test.ignoreFile != "" {
+				files[promptIgnoreFileName] = test.ignoreFile
+			}
+
+			store := NewFSStore(newFilterTestFs(files), test.options...)
+
+			promptFiles, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if names := promptFileNames(promptFiles); !slices.Equal(names, test.expectedNames) {
+				t.Errorf("Expected prompt files %v, got %v", test.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestFSStore_Load_WithLenient(t *testing.T) {
+	store := NewFSStore(newFilterTestFs(nil), WithLenient())
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"draft", "escalation", "notes", "root", "support"}
+	if names := promptFileNames(promptFiles); !slices.Equal(names, expected) {
+		t.Errorf("Expected prompt files %v, got %v", expected, names)
+	}
+
+	loadErrors := store.Errors()
+	if len(loadErrors) != 1 {
+		t.Fatalf("Expected 1 load error, got %d", len(loadErrors))
+	}
+
+	if loadErrors[0].Path != "team/nested/broken.prompt" {
+		t.Errorf("Expected error for team/nested/broken.prompt, got %s", loadErrors[0].Path)
+	}
+
+	var promptError *PromptError
+	if !errors.As(loadErrors[0], &promptError) {
+		t.Errorf("Expected PromptError, got %T", loadErrors[0].Err)
+	}
+}
+
+func TestFSStore_Load_WithoutLenient_ReturnsError(t *testing.T) {
+	store := NewFSStore(newFilterTestFs(nil))
+
+	if _, err := store.Load(); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+}
+
+func TestFSStore_Load_WithInvalidPattern_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       []LoaderOption
+		expectedError string
+	}{
+		{"invalid-include", []LoaderOption{WithInclude("[")}, "invalid include pattern: ["},
+		{"negated-include", []LoaderOption{WithInclude("!*.prompt")}, "invalid include pattern: !*.prompt"},
+		{"invalid-exclude", []LoaderOption{WithExclude("team/[")}, "invalid exclude pattern: team/["},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewFSStore(newFilterTestFs(nil), test.options...).Load()
+
+			var storeError *FileStoreError
+			if !errors.As(err, &storeError) {
+				t.Fatalf("Expected FileStoreError, got %T", err)
+			}
+
+			if storeError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, storeError.Error())
+			}
+		})
+	}
+}
+
+func TestFileStore_Load_WithSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	writeFile := func(name string, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(filepath.Join(root, "local.prompt"), "name: local\n"+minimalPrompt)
+	writeFile(filepath.Join(outside, "linked.prompt"), "name: linked\n"+minimalPrompt)
+	writeFile(filepath.Join(outside, "shared", "shared.prompt"), "name: shared\n"+minimalPrompt)
+
+	links := map[string]string{
+		filepath.Join(outside, "linked.prompt"): filepath.Join(root, "linked.prompt"),
+		filepath.Join(outside, "shared"):        filepath.Join(root, "shared"),
+		root:                                    filepath.Join(root, "loop"),
+	}
+	for target, link := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("Symbolic links are not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		mode          SymlinkMode
+		expectedNames []string
+	}{
+		{"files", SymlinkFiles, []string{"linked", "local"}},
+		{"follow", SymlinkFollow, []string{"linked", "local", "shared"}},
+		{"skip", SymlinkSkip, []string{"local"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store, err := NewFileStoreFromPath(root, WithSymlinks(test.mode))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			promptFiles, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if names := promptFileNames(promptFiles); !slices.Equal(names, test.expectedNames) {
+				t.Errorf("Expected prompt files %v, got %v", test.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestFileStore_Load_WithLenient(t *testing.T) {
+	store, err := NewFileStoreFromPath("test-data", WithLenient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) == 0 {
+		t.Error("Expected the valid prompt files to be loaded")
+	}
+
+	expectedPath := filepath.Join("test-data", "basic-broken.prompt")
+	loadErrors := store.Errors()
+	if !slices.ContainsFunc(loadErrors, func(e LoadError) bool { return e.Path == expectedPath }) {
+		t.Errorf("Expected an error for %s, got %v", expectedPath, loadErrors)
+	}
+}
+
+func TestGlobPattern_Matches(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"*.prompt", "a/b/c.prompt", false, true},
+		{"/*.prompt", "a/c.prompt", false, false},
+		{"/*.prompt", "c.prompt", false, true},
+		{"a/*.prompt", "a/c.prompt", false, true},
+		{"a/*.prompt", "x/a/c.prompt", false, false},
+		{"**/c.prompt", "c.prompt", false, true},
+		{"a/**/c.prompt", "a/b/b/c.prompt", false, true},
+		{"a/**", "a/b/c.prompt", false, true},
+		{"drafts/", "drafts", true, true},
+		{"drafts/", "drafts", false, false},
+	}
+
+	for _, test := range tests {
+		parsed, err := parseGlobPattern(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if matched := parsed.matches(test.path, test.isDir); matched != test.expected {
+			t.Errorf("Expected pattern '%s' matching '%s' to be %t, got %t", test.pattern, test.path, test.expected, matched)
+		}
+	}
+}
+
+// newLargePromptTree creates a file system containing the number of prompt files, spread across nested directories,
+// where every file at an index in invalid is missing its user prompt.
+func newLargePromptTree(count int, invalid ...int) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for i := 0; i < count; i++ {
+		content := fmt.Sprintf("name: prompt-%04d\nconfig:\n  input:\n    parameters:\n      topic: string\nprompts:\n  system: You are helpful\n  user: Tell me about {{ topic }}\n", i)
+		if slices.Contains(invalid, i) {
+			content = fmt.Sprintf("name: prompt-%04d\nprompts:\n  system: No user prompt\n", i)
+		}
+		fsys[fmt.Sprintf("group-%02d/sub-%d/prompt-%04d.prompt", i%10, i%3, i)] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func TestFSStore_Load_WithConcurrency_IsDeterministic(t *testing.T) {
+	fsys := newLargePromptTree(300)
+
+	sequential, err := NewFSStore(fsys, WithConcurrency(1)).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{2, 8, 64} {
+		concurrent, loadErr := NewFSStore(fsys, WithConcurrency(workers)).Load()
+		if loadErr != nil {
+			t.Fatal(loadErr)
+		}
+
+		if len(concurrent) != len(sequential) {
+			t.Fatalf("Expected %d prompt files, got %d", len(sequential), len(concurrent))
+		}
+
+		for i := range sequential {
+			if concurrent[i].Name != sequential[i].Name {
+				t.Fatalf("Expected prompt file %d to be %s with %d workers, got %s", i, sequential[i].Name, workers, concurrent[i].Name)
+			}
+		}
+	}
+}
+
+func TestFSStore_Load_WithConcurrency_ReturnsFirstError(t *testing.T) {
+	fsys := newLargePromptTree(300, 25, 150, 299)
+
+	_, expected := NewFSStore(fsys, WithConcurrency(1)).Load()
+	if expected == nil {
+		t.Fatal("Expected error, got none")
+	}
+
+	for i := 0; i < 20; i++ {
+		_, err := NewFSStore(fsys, WithConcurrency(8)).Load()
+		if err == nil || err.Error() != expected.Error() {
+			t.Fatalf("Expected error '%v', got '%v'", expected, err)
+		}
+	}
+
+	store := NewFSStore(fsys, WithConcurrency(8), WithLenient())
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 297 || len(store.Errors()) != 3 {
+		t.Errorf("Expected 297 prompt files and 3 errors, got %d and %d", len(promptFiles), len(store.Errors()))
+	}
+}
+
+func TestPromptWalker_Walk_WithCancelledContext(t *testing.T) {
+	walker, err := newPromptWalker(newLargePromptTree(10), "", newLoaderOptions(nil), func(string) (*PromptFile, error) {
+		return &PromptFile{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err = walker.walk(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// loadInline loads every prompt file in the file system as it is walked, without a pool of workers, as stores did
+// before loading concurrently. It gives a baseline for the benchmarks.
+func loadInline(fsys fs.FS) ([]PromptFile, error) {
+	promptFiles := make([]PromptFile, 0)
+	err := fs.WalkDir(fsys, ".", func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil || entry.IsDir() || !isPromptFilePath(filePath) {
+			return walkErr
+		}
+
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+
+		promptFile, err := newPromptFileFromSource(path.Base(filePath), content, SourceMetadata{Path: filePath, Loader: FSSource})
+		if err != nil {
+			return err
+		}
+
+		promptFiles = append(promptFiles, *promptFile)
+		return nil
+	})
+	return promptFiles, err
+}
+
+func BenchmarkFileStore_Load(b *testing.B) {
+	root := b.TempDir()
+	for name, file := range newLargePromptTree(1000) {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(target, file.Data, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	benchmarks := []struct {
+		name    string
+		workers int
+	}{
+		{"sequential", 1},
+		{"concurrent", runtime.GOMAXPROCS(0)},
+	}
+
+	b.Run("baseline", func(b *testing.B) {
+		fsys := os.DirFS(root)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := loadInline(fsys); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			store, err := NewFileStoreFromPath(root, WithConcurrency(benchmark.workers))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err = store.Load(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFSStore_Load(b *testing.B) {
+	fsys := newLargePromptTree(1000)
+
+	benchmarks := []struct {
+		name    string
+		workers int
+	}{
+		{"sequential", 1},
+		{"concurrent", runtime.GOMAXPROCS(0)},
+	}
+
+	b.Run("baseline", func(b *testing.B) {
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := loadInline(fsys); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			store := NewFSStore(fsys, WithConcurrency(benchmark.workers))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.Load(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
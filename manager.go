@@ -1,6 +1,11 @@
 package dotprompt
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
 
 // Loader defines an interface for loading prompt files.
 type Loader interface {
@@ -9,13 +14,109 @@ type Loader interface {
 	Load() ([]PromptFile, error)
 }
 
+// ContextLoader defines an interface for loading prompt files which can be cancelled, or limited in time, using a
+// context.
+type ContextLoader interface {
+
+	// LoadContext loads prompt files and returns a slice of PromptFile and an error, stopping early if the context is
+	// done.
+	LoadContext(ctx context.Context) ([]PromptFile, error)
+}
+
+// contextLoaderAdapter adapts a Loader which does not support a context into a ContextLoader.
+type contextLoaderAdapter struct {
+	loader Loader
+}
+
+// NewContextLoader returns a ContextLoader for the loader. If the loader already implements ContextLoader then it is
+// returned unchanged. Otherwise, LoadContext returns the error of the context as soon as it is done, but the call to
+// Load continues in the background until it completes, and its result is discarded.
+func NewContextLoader(loader Loader) ContextLoader {
+	if contextLoader, ok := loader.(ContextLoader); ok {
+		return contextLoader
+	}
+
+	return &contextLoaderAdapter{loader: loader}
+}
+
+// LoadContext loads the prompt files using the adapted loader, returning early if the context is done.
+func (a *contextLoaderAdapter) LoadContext(ctx context.Context) ([]PromptFile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type loadResult struct {
+		promptFiles []PromptFile
+		err         error
+	}
+
+	results := make(chan loadResult, 1)
+	go func() {
+		promptFiles, err := a.loader.Load()
+		results <- loadResult{promptFiles: promptFiles, err: err}
+	}()
+
+	select {
+	case result := <-results:
+		return result.promptFiles, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // Manager is responsible for managing and storing prompt files, with mapping from their names to PromptFile instances.
 type Manager struct {
-	PromptFiles map[string]PromptFile
+	PromptFiles  map[string]PromptFile
+	profile      string
+	modelAliases map[string]string
+	coerce       bool
 }
 
-// GetPromptFile retrieves the prompt file with the specified name from the manager's stored prompt files.
-// Returns the PromptFile and a boolean indicating success of the retrieval.
+// ManagerOption configures a Manager when it is created.
+type ManagerOption func(m *Manager)
+
+// WithProfile sets the active profile of the Manager, such as "dev" or "prod", so that GetPromptFile returns prompt
+// files with the overrides of the profile applied. Creating the Manager fails if no prompt file has overrides for the
+// profile, which catches a misspelled profile.
+func WithProfile(profile string) ManagerOption {
+	return func(m *Manager) {
+		m.profile = profile
+	}
+}
+
+// WithParameterCoercion sets GetPromptFile to return prompt files which convert loosely typed parameter values to their
+// declared type, in the same way as WithCoercion, regardless of the loader the prompt files were loaded from.
+func WithParameterCoercion() ManagerOption {
+	return func(m *Manager) {
+		m.coerce = true
+	}
+}
+
+// Profile returns the active profile of the Manager, or an empty string if no profile was set.
+func (m *Manager) Profile() string {
+	return m.profile
+}
+
+// Profiles returns the sorted names of the profiles which any of the prompt files managed by the Manager has overrides
+// for.
+func (m *Manager) Profiles() []string {
+	profiles := make([]string, 0)
+	for _, promptFile := range m.PromptFiles {
+		for profile := range promptFile.Overrides {
+			if !slices.Contains(profiles, profile) {
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	slices.Sort(profiles)
+	return profiles
+}
+
+// GetPromptFile retrieves the prompt file with the specified name from the manager's stored prompt files, with the
+// overrides of the active profile applied, any model alias replaced by the model it refers to, and parameter coercion
+// enabled if set using WithParameterCoercion. A prompt file with a profile or model alias resolved returns an error when
+// serialized, so that the resolved values are not written in place of its own. Returns the PromptFile and an error if
+// it is not found.
 func (m *Manager) GetPromptFile(name string) (PromptFile, error) {
 	promptFile, ok := m.PromptFiles[name]
 	if !ok {
@@ -23,6 +124,24 @@ func (m *Manager) GetPromptFile(name string) (PromptFile, error) {
 			Message: fmt.Sprintf("prompt file not found: %s", name),
 		}
 	}
+
+	if m.profile != "" {
+		promptFile = promptFile.ResolveProfile(m.profile)
+	}
+
+	model, err := m.ResolveModel(promptFile.Model)
+	if err != nil {
+		return PromptFile{}, err
+	}
+	if model != promptFile.Model {
+		promptFile.Model = model
+		promptFile.resolved = true
+	}
+
+	if m.coerce {
+		promptFile.coerce = true
+	}
+
 	return promptFile, nil
 }
 
@@ -35,20 +154,52 @@ func (m *Manager) ListPromptFileNames() []string {
 	return names
 }
 
-// NewManager creates a new Manager by loading prompt files from the default file store.
+// ListSources returns a map from the name of each prompt file managed by the Manager to the metadata describing where
+// it was loaded from.
+func (m *Manager) ListSources() map[string]SourceMetadata {
+	sources := make(map[string]SourceMetadata, len(m.PromptFiles))
+	for name, promptFile := range m.PromptFiles {
+		sources[name] = promptFile.Source
+	}
+	return sources
+}
+
+// ListNamespace returns the sorted names of the prompt files within the namespace, including those in nested
+// namespaces, when the prompt files were loaded using WithNamespaces. An empty namespace returns the names of the
+// prompt files which are not in any namespace.
+func (m *Manager) ListNamespace(namespace string) []string {
+	namespace = strings.Trim(namespace, "/")
+
+	names := make([]string, 0)
+	for name := range m.PromptFiles {
+		inNamespace := strings.HasPrefix(name, namespace+"/")
+		if namespace == "" {
+			inNamespace = !strings.Contains(name, "/")
+		}
+
+		if inNamespace {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// NewManager creates a new Manager by loading prompt files from the default file store, with the options applied.
 // Returns a pointer to the Manager instance or an error if the loading process fails.
-func NewManager() (*Manager, error) {
+func NewManager(options ...ManagerOption) (*Manager, error) {
 	loader, err := NewFileStore()
 	if err != nil {
 		return nil, err
 	}
 
-	return NewManagerFromLoader(loader)
+	return NewManagerFromLoader(loader, options...)
 }
 
 // NewManagerFromLoader initializes and returns a Manager instance by loading prompt files using the provided Loader.
 // It returns a pointer to the Manager and an error if the loading process fails.
-func NewManagerFromLoader(loader Loader) (*Manager, error) {
+func NewManagerFromLoader(loader Loader, options ...ManagerOption) (*Manager, error) {
 	if loader == nil {
 		return nil, &PromptError{
 			Message: "loader cannot be nil",
@@ -60,6 +211,30 @@ func NewManagerFromLoader(loader Loader) (*Manager, error) {
 		return nil, err
 	}
 
+	return newManagerFromPromptFiles(promptFiles, options)
+}
+
+// NewManagerFromLoaderContext initializes and returns a Manager instance by loading prompt files using the provided
+// ContextLoader, which stops loading if the context is done. Use NewContextLoader to pass a Loader which does not
+// support a context.
+func NewManagerFromLoaderContext(ctx context.Context, loader ContextLoader, options ...ManagerOption) (*Manager, error) {
+	if loader == nil {
+		return nil, &PromptError{
+			Message: "loader cannot be nil",
+		}
+	}
+
+	promptFiles, err := loader.LoadContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newManagerFromPromptFiles(promptFiles, options)
+}
+
+// newManagerFromPromptFiles creates a Manager from the loaded prompt files with the options applied, returning an error
+// if more than one has the same name or a prompt file refers to an unknown model alias.
+func newManagerFromPromptFiles(promptFiles []PromptFile, options []ManagerOption) (*Manager, error) {
 	promptFilesMap := make(map[string]PromptFile)
 	for _, promptFile := range promptFiles {
 		if _, ok := promptFilesMap[promptFile.Name]; ok {
@@ -70,7 +245,22 @@ func NewManagerFromLoader(loader Loader) (*Manager, error) {
 		promptFilesMap[promptFile.Name] = promptFile
 	}
 
-	return &Manager{
+	manager := &Manager{
 		PromptFiles: promptFilesMap,
-	}, nil
+	}
+	for _, option := range options {
+		option(manager)
+	}
+
+	if manager.profile != "" && !slices.Contains(manager.Profiles(), manager.profile) {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("unknown profile: %s", manager.profile),
+		}
+	}
+
+	if err := manager.validateModelAliases(); err != nil {
+		return nil, err
+	}
+
+	return manager, nil
 }
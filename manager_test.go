@@ -1,9 +1,12 @@
 package dotprompt
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"testing"
+	"time"
 )
 
 type MockLoader struct {
@@ -163,6 +166,111 @@ func TestGetPromptFile_WithInvalidPromptName(t *testing.T) {
 	}
 }
 
+func TestGetPromptFile_WithProfile(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-overrides.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile       string
+		expectedModel string
+	}{
+		{"", "gpt-4o-mini"},
+		{"prod", "gpt-4o"},
+		{"dev", "gpt-4o-mini"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			mgr, err := NewManagerFromLoader(&MockLoader{PromptFiles: []PromptFile{*promptFile}}, WithProfile(test.profile))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if mgr.Profile() != test.profile {
+				t.Errorf("Expected profile '%s', got '%s'", test.profile, mgr.Profile())
+			}
+
+			resolved, err := mgr.GetPromptFile(promptFile.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resolved.Model != test.expectedModel {
+				t.Errorf("Expected model '%s', got '%s'", test.expectedModel, resolved.Model)
+			}
+
+			if mgr.PromptFiles[promptFile.Name].Model != "gpt-4o-mini" {
+				t.Error("Expected the stored prompt file to be unchanged")
+			}
+		})
+	}
+}
+
+func TestNewManagerFromLoader_WithUnknownProfile_ReturnsError(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-overrides.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &MockLoader{PromptFiles: []PromptFile{*promptFile}}
+
+	mgr, err := NewManagerFromLoader(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if profiles := mgr.Profiles(); !slices.Equal(profiles, []string{"dev", "prod"}) {
+		t.Errorf("Expected profiles [dev prod], got %v", profiles)
+	}
+
+	_, err = NewManagerFromLoader(loader, WithProfile("prdo"))
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	if promptError.Error() != "unknown profile: prdo" {
+		t.Errorf("Expected error 'unknown profile: prdo', got '%s'", promptError.Error())
+	}
+}
+
+func TestGetPromptFile_WithProfile_CannotBeSerialized(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-overrides.prompt", WithEditMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := NewManagerFromLoader(&MockLoader{PromptFiles: []PromptFile{*promptFile}}, WithProfile("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := mgr.GetPromptFile(promptFile.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = resolved.Serialize()
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expected := "prompt file with-overrides has a profile or model alias resolved and cannot be serialized"
+	if promptError.Error() != expected {
+		t.Errorf("Expected error '%s', got '%s'", expected, promptError.Error())
+	}
+
+	stored := mgr.PromptFiles[promptFile.Name]
+	if _, err = stored.Serialize(); err != nil {
+		t.Errorf("Expected the stored prompt file to serialize, got %v", err)
+	}
+}
+
 // ExampleNewManager demonstrates the process of creating a new Manager instance which loads from the default "prompts"
 // directory, and then fetching a prompt by name from the manager.
 func ExampleNewManager() {
@@ -179,3 +287,107 @@ func ExampleNewManager() {
 	fmt.Println(promptFile.Prompts.System)
 	// Output: You are a helpful research assistant who will provide descriptive responses for a given topic and how it impacts society
 }
+
+func TestManager_ListNamespace(t *testing.T) {
+	mgr := &Manager{
+		PromptFiles: map[string]PromptFile{
+			"root":                     {Name: "root"},
+			"support/summarise":        {Name: "support/summarise"},
+			"sales/summarise":          {Name: "sales/summarise"},
+			"sales/eu-team/cold-pitch": {Name: "sales/eu-team/cold-pitch"},
+			"salesforce/sync":          {Name: "salesforce/sync"},
+		},
+	}
+
+	tests := []struct {
+		namespace string
+		expected  []string
+	}{
+		{"sales", []string{"sales/eu-team/cold-pitch", "sales/summarise"}},
+		{"/sales/eu-team/", []string{"sales/eu-team/cold-pitch"}},
+		{"", []string{"root"}},
+		{"missing", []string{}},
+	}
+
+	for _, test := range tests {
+		if names := mgr.ListNamespace(test.namespace); !slices.Equal(names, test.expected) {
+			t.Errorf("Expected namespace '%s' to contain %v, got %v", test.namespace, test.expected, names)
+		}
+	}
+
+	if _, err := mgr.GetPromptFile("sales/summarise"); err != nil {
+		t.Error(err)
+	}
+}
+
+// SlowLoader is a Loader which does not support a context and takes the delay to load.
+type SlowLoader struct {
+	Delay time.Duration
+}
+
+func (s *SlowLoader) Load() ([]PromptFile, error) {
+	time.Sleep(s.Delay)
+	return []PromptFile{{Name: "slow"}}, nil
+}
+
+func TestNewManagerFromLoaderContext(t *testing.T) {
+	mgr, err := NewManagerFromLoaderContext(context.Background(), NewFSStore(validFs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mgr.PromptFiles) != 2 {
+		t.Errorf("Expected 2 prompt files, got %d", len(mgr.PromptFiles))
+	}
+}
+
+func TestNewManagerFromLoaderContext_WithCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name   string
+		loader ContextLoader
+	}{
+		{"fs-store", NewFSStore(validFs)},
+		{"adapted-loader", NewContextLoader(&SlowLoader{})},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewManagerFromLoaderContext(ctx, test.loader); !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewManagerFromLoaderContext_WithNilLoader(t *testing.T) {
+	if _, err := NewManagerFromLoaderContext(context.Background(), nil); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+}
+
+func TestNewContextLoader(t *testing.T) {
+	store := NewFSStore(validFs)
+	if loader := NewContextLoader(store); loader != ContextLoader(store) {
+		t.Error("Expected a ContextLoader to be returned unchanged")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := NewContextLoader(&SlowLoader{Delay: time.Second}).LoadContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	promptFiles, err := NewContextLoader(&SlowLoader{}).LoadContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 1 {
+		t.Errorf("Expected 1 prompt file, got %d", len(promptFiles))
+	}
+}
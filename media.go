@@ -0,0 +1,255 @@
+package dotprompt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxMediaSize is the maximum size, in bytes, of an image or file parameter when the prompt file does not
+// specify its own limit.
+const DefaultMaxMediaSize = 20 * 1024 * 1024
+
+var (
+	mediaMarkerRegex = regexp.MustCompile(`\[\[dotprompt:media:([^\]]+)\]\]`)
+	validImageTypes  = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+)
+
+// ContentPartType represents the type of content held by a ContentPart.
+type ContentPartType string
+
+const (
+
+	// TextContent represents a part containing plain text.
+	TextContent ContentPartType = "text"
+
+	// ImageContent represents a part containing an image.
+	ImageContent ContentPartType = "image"
+
+	// FileContent represents a part containing a document, such as a PDF.
+	FileContent ContentPartType = "file"
+)
+
+// ContentPart represents a single part of a message, which is either text or binary media such as an image or file.
+type ContentPart struct {
+	Type     ContentPartType `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	Data     []byte          `json:"data,omitempty"`
+}
+
+// DataURI returns the media held by the part encoded as a base64 data URI.
+func (cp ContentPart) DataURI() string {
+	return fmt.Sprintf("data:%s;base64,%s", cp.MimeType, base64.StdEncoding.EncodeToString(cp.Data))
+}
+
+// GetUserContent generates the user prompt as a list of content parts. Image and file parameters are placed at the
+// position in the template where they are referenced, with the surrounding text split into text parts.
+func (pf *PromptFile) GetUserContent(values map[string]interface{}) ([]ContentPart, error) {
+	prompt, media, err := pf.renderTemplate(pf.userTemplate(), values)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitContentParts(prompt, media), nil
+}
+
+// splitContentParts splits a rendered prompt containing media markers into text and media content parts. Text which
+// is only whitespace is dropped.
+func splitContentParts(prompt string, media map[string]ContentPart) []ContentPart {
+	parts := make([]ContentPart, 0)
+	appendText := func(text string) {
+		if len(strings.TrimSpace(text)) > 0 {
+			parts = append(parts, ContentPart{Type: TextContent, Text: text})
+		}
+	}
+
+	position := 0
+	for _, match := range mediaMarkerRegex.FindAllStringSubmatchIndex(prompt, -1) {
+		appendText(prompt[position:match[0]])
+		if part, ok := media[prompt[match[2]:match[3]]]; ok {
+			parts = append(parts, part)
+		}
+		position = match[1]
+	}
+	appendText(prompt[position:])
+
+	return parts
+}
+
+// mediaMarker returns the placeholder which is bound to a media parameter when rendering a template.
+func mediaMarker(name string) string {
+	return fmt.Sprintf("[[dotprompt:media:%s]]", name)
+}
+
+// removeMediaMarkers removes any media placeholders from a rendered prompt.
+func removeMediaMarkers(prompt string) string {
+	return mediaMarkerRegex.ReplaceAllString(prompt, "")
+}
+
+// maxMediaSize returns the maximum size of a media parameter for the prompt file.
+func (pf *PromptFile) maxMediaSize() int {
+	if pf.Config.Input.MaxMediaSize > 0 {
+		return pf.Config.Input.MaxMediaSize
+	}
+	return DefaultMaxMediaSize
+}
+
+// loadMedia converts a parameter value into a media content part. The value may be a byte slice, a data URI, or the
+// path to a file within the media root when one is set. The MIME type is sniffed from the content and validated against
+// the parameter type.
+func (pf *PromptFile) loadMedia(key string, parameterType string, value interface{}) (ContentPart, error) {
+	var data []byte
+	var declaredType string
+	name := key
+	maxSize := pf.maxMediaSize()
+
+	switch typedValue := value.(type) {
+	case []byte:
+		data = typedValue
+	case string:
+		if strings.HasPrefix(typedValue, "data:") {
+			var err error
+			declaredType, data, err = parseDataURI(typedValue)
+			if err != nil {
+				return ContentPart{}, &PromptError{
+					Message: fmt.Sprintf("parameter %s is not a valid data URI: %v", key, err),
+				}
+			}
+		} else {
+			var err error
+			if data, err = pf.readMediaFile(key, typedValue, maxSize); err != nil {
+				return ContentPart{}, err
+			}
+			name = path.Base(filepath.ToSlash(typedValue))
+		}
+	default:
+		return ContentPart{}, &PromptError{
+			Message: fmt.Sprintf("parameter %s is not a %s", key, parameterType),
+		}
+	}
+
+	if len(data) == 0 {
+		return ContentPart{}, &PromptError{
+			Message: fmt.Sprintf("parameter %s is empty", key),
+		}
+	}
+
+	if len(data) > maxSize {
+		return ContentPart{}, &PromptError{
+			Message: fmt.Sprintf("parameter %s exceeds the maximum size of %d bytes", key, maxSize),
+		}
+	}
+
+	// Prefer the sniffed type, but fall back to the type declared by a data URI when the content is not recognised
+	mimeType := strings.Split(http.DetectContentType(data), ";")[0]
+	if mimeType == "application/octet-stream" && len(declaredType) > 0 {
+		mimeType = declaredType
+	}
+
+	partType := FileContent
+	if parameterType == "image" {
+		partType = ImageContent
+		if !slices.Contains(validImageTypes, mimeType) {
+			return ContentPart{}, &PromptError{
+				Message: fmt.Sprintf("parameter %s is not a supported image type: %s", key, mimeType),
+			}
+		}
+	}
+
+	return ContentPart{Type: partType, Name: name, MimeType: mimeType, Data: data}, nil
+}
+
+// readMediaFile reads the file at the path relative to the media root of the prompt file. Returns an error if no media
+// root is set, if the path leaves the media root, or if the file is larger than the maximum size.
+func (pf *PromptFile) readMediaFile(key string, filePath string, maxSize int) ([]byte, error) {
+	if pf.mediaRoot == "" {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s must be a byte slice or a data URI, as no media root is set", key),
+		}
+	}
+
+	slashPath := filepath.ToSlash(filePath)
+	if !fs.ValidPath(slashPath) {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s is not a path within the media root: %s", key, filePath),
+		}
+	}
+
+	// Symbolic links are resolved so that a link within the root cannot be used to read a file outside of it
+	root, err := filepath.EvalSymlinks(pf.mediaRoot)
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s could not be read: %v", key, err),
+		}
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(slashPath)))
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s could not be read: %v", key, err),
+		}
+	}
+
+	relative, err := filepath.Rel(root, resolved)
+	if err != nil || !fs.ValidPath(filepath.ToSlash(relative)) {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s is not a path within the media root: %s", key, filePath),
+		}
+	}
+
+	rootFs := os.DirFS(root)
+	info, err := fs.Stat(rootFs, filepath.ToSlash(relative))
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s could not be read: %v", key, err),
+		}
+	}
+	if info.Size() > int64(maxSize) {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s exceeds the maximum size of %d bytes", key, maxSize),
+		}
+	}
+
+	data, err := fs.ReadFile(rootFs, filepath.ToSlash(relative))
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("parameter %s could not be read: %v", key, err),
+		}
+	}
+	return data, nil
+}
+
+// parseDataURI parses a data URI, returning the declared MIME type and the decoded data.
+func parseDataURI(uri string) (string, []byte, error) {
+	header, content, found := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !found {
+		return "", nil, fmt.Errorf("missing data separator")
+	}
+
+	mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+	mimeType := strings.Split(mediaType, ";")[0]
+
+	if isBase64 {
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return "", nil, err
+		}
+		return mimeType, data, nil
+	}
+
+	decoded, err := url.PathUnescape(content)
+	if err != nil {
+		return "", nil, err
+	}
+	return mimeType, []byte(decoded), nil
+}
@@ -0,0 +1,285 @@
+package dotprompt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptFile_GetUserContent_WithImagePath(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/multimodal.prompt", WithMediaRoot("."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := promptFile.GetUserContent(map[string]interface{}{"photo": "test-data/media/pixel.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 content parts, got %d", len(parts))
+	}
+
+	if parts[0].Type != TextContent || parts[0].Text != "Describe this image for an audience of children\n" {
+		t.Errorf("Expected first part to be the text prompt, got %+v", parts[0])
+	}
+
+	if parts[1].Type != ImageContent || parts[1].MimeType != "image/png" || parts[1].Name != "pixel.png" {
+		t.Errorf("Expected second part to be a png image, got type '%s', mime type '%s', name '%s'", parts[1].Type, parts[1].MimeType, parts[1].Name)
+	}
+}
+
+func TestPromptFile_GetUserContent_WithAllValueKinds(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/multimodal.prompt", WithMediaRoot("."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image, err := os.ReadFile("test-data/media/pixel.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"bytes", image},
+		{"path", "test-data/media/pixel.png"},
+		{"base64-data-uri", "data:image/png;base64," + base64.StdEncoding.EncodeToString(image)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			parts, err := promptFile.GetUserContent(map[string]interface{}{"photo": test.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(parts[1].Data, image) {
+				t.Error("Expected image data to match the source image")
+			}
+		})
+	}
+}
+
+func TestPromptFile_GetUserContent_WithFile(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/multimodal.prompt", WithMediaRoot("."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := promptFile.GetUserContent(map[string]interface{}{
+		"photo":    "test-data/media/pixel.png",
+		"notes":    "data:text/plain,Penguins%20are%20flightless%20birds.",
+		"audience": "adults",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parts) != 4 {
+		t.Fatalf("Expected 4 content parts, got %d", len(parts))
+	}
+
+	if parts[2].Text != "\nUse these notes to help\n" {
+		t.Errorf("Expected third part to be 'Use these notes to help', got '%s'", parts[2].Text)
+	}
+
+	if parts[3].Type != FileContent || parts[3].MimeType != "text/plain" || string(parts[3].Data) != "Penguins are flightless birds." {
+		t.Errorf("Expected fourth part to be a text file, got %+v", parts[3])
+	}
+}
+
+func TestPromptFile_GetUserPrompt_WithMedia_OmitsMedia(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/multimodal.prompt", WithMediaRoot("."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := promptFile.GetUserPrompt(map[string]interface{}{"photo": "test-data/media/pixel.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Describe this image for an audience of children\n\n"
+	if prompt != expected {
+		t.Errorf("Expected prompt to be '%s', got '%s'", expected, prompt)
+	}
+}
+
+func TestPromptFile_GetUserContent_WithInvalidMedia_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        map[string]interface{}
+		expectedError string
+	}{
+		{"wrong-type", map[string]interface{}{"photo": 12}, "parameter photo is not a image"},
+		{"missing-path", map[string]interface{}{"photo": "test-data/media/missing.png"}, "parameter photo could not be read"},
+		{"not-an-image", map[string]interface{}{"photo": "test-data/media/notes.txt"}, "parameter photo is not a supported image type: text/plain"},
+		{"empty", map[string]interface{}{"photo": []byte{}}, "parameter photo is empty"},
+		{"invalid-data-uri", map[string]interface{}{"photo": "data:image/png;base64"}, "parameter photo is not a valid data URI"},
+		{"invalid-base64", map[string]interface{}{"photo": "data:image/png;base64,!!!"}, "parameter photo is not a valid data URI"},
+		{"too-large-bytes", map[string]interface{}{"photo": bytes.Repeat([]byte{0}, 1025)}, "parameter photo exceeds the maximum size of 1024 bytes"},
+		{"too-large-file", map[string]interface{}{"photo": "test-data/media/pixel.png", "notes": "LICENSE.txt"}, "parameter notes exceeds the maximum size of 1024 bytes"},
+	}
+
+	promptFile, err := NewPromptFileFromFile("test-data/multimodal.prompt", WithMediaRoot("."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := promptFile.GetUserContent(test.values)
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if !strings.HasPrefix(promptError.Error(), test.expectedError) {
+				t.Errorf("Expected error to start with '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestPromptFile_GetUserContent_WithoutMediaRoot_RejectsPaths(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/multimodal.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = promptFile.GetUserContent(map[string]interface{}{"photo": "test-data/media/pixel.png"})
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expected := "parameter photo must be a byte slice or a data URI, as no media root is set"
+	if promptError.Error() != expected {
+		t.Errorf("Expected error '%s', got '%s'", expected, promptError.Error())
+	}
+}
+
+func TestPromptFile_GetUserContent_WithMediaRoot_RejectsPathsOutsideRoot(t *testing.T) {
+	absolute, err := filepath.Abs("test-data/media/pixel.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	if err = os.Symlink(absolute, filepath.Join(root, "link.png")); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+
+	promptFile, err := NewPromptFileFromFile("test-data/multimodal.prompt", WithMediaRoot(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"parent", "../pixel.png"},
+		{"absolute", absolute},
+		{"symlink", "link.png"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := promptFile.GetUserContent(map[string]interface{}{"photo": test.value})
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			expected := "parameter photo is not a path within the media root: " + test.value
+			if promptError.Error() != expected {
+				t.Errorf("Expected error '%s', got '%s'", expected, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestOpenAIContent(t *testing.T) {
+	parts := []ContentPart{
+		{Type: TextContent, Text: "Describe this"},
+		{Type: ImageContent, MimeType: "image/png", Data: []byte("png")},
+		{Type: FileContent, Name: "doc.pdf", MimeType: "application/pdf", Data: []byte("pdf")},
+	}
+
+	content := OpenAIContent(parts)
+
+	if content[0].Type != "text" || content[0].Text != "Describe this" {
+		t.Errorf("Unexpected text part: %+v", content[0])
+	}
+
+	if content[1].Type != "image_url" || content[1].ImageURL.URL != "data:image/png;base64,cG5n" {
+		t.Errorf("Unexpected image part: %+v", content[1])
+	}
+
+	if content[2].Type != "file" || content[2].File.Filename != "doc.pdf" || content[2].File.FileData != "data:application/pdf;base64,cGRm" {
+		t.Errorf("Unexpected file part: %+v", content[2])
+	}
+}
+
+func TestAnthropicContent(t *testing.T) {
+	parts := []ContentPart{
+		{Type: TextContent, Text: "Describe this"},
+		{Type: ImageContent, MimeType: "image/png", Data: []byte("png")},
+		{Type: FileContent, Name: "doc.pdf", MimeType: "application/pdf", Data: []byte("pdf")},
+		{Type: FileContent, Name: "notes.txt", MimeType: "text/plain", Data: []byte("notes")},
+	}
+
+	content := AnthropicContent(parts)
+
+	if content[0].Type != "text" || content[0].Text != "Describe this" {
+		t.Errorf("Unexpected text block: %+v", content[0])
+	}
+
+	if content[1].Type != "image" || content[1].Source.Type != "base64" || content[1].Source.MediaType != "image/png" || content[1].Source.Data != "cG5n" {
+		t.Errorf("Unexpected image block: %+v", content[1])
+	}
+
+	if content[2].Type != "document" || content[2].Source.Type != "base64" || content[2].Title != "doc.pdf" {
+		t.Errorf("Unexpected pdf block: %+v", content[2])
+	}
+
+	if content[3].Type != "document" || content[3].Source.Type != "text" || content[3].Source.Data != "notes" {
+		t.Errorf("Unexpected text document block: %+v", content[3])
+	}
+}
+
+func TestPromptFile_CacheKey_WithDifferentImages(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/multimodal.prompt", WithMediaRoot("."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := promptFile.CacheKey(map[string]interface{}{"photo": "test-data/media/pixel.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := promptFile.CacheKey(map[string]interface{}{"photo": "data:image/gif;base64,R0lGODlhAQABAAAAACw="})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Error("Expected cache keys for different images to differ")
+	}
+}
@@ -0,0 +1,261 @@
+package dotprompt
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const jsonInstruction string = "Please provide the response in JSON"
+
+const (
+
+	// SystemRole represents a message containing instructions for the model.
+	SystemRole string = "system"
+
+	// UserRole represents a message from the user.
+	UserRole string = "user"
+
+	// AssistantRole represents a message from the model.
+	AssistantRole string = "assistant"
+
+	// ToolRole represents a message containing the result of a tool call.
+	ToolRole string = "tool"
+
+	// HistoryRole represents a placeholder in the messages list where prior conversation history is inserted when
+	// the messages are rendered.
+	HistoryRole string = "history"
+)
+
+var validMessageRoles = []string{SystemRole, UserRole, AssistantRole, ToolRole, HistoryRole}
+
+// MessageTemplate represents a single templated turn in the messages list of a prompt file.
+type MessageTemplate struct {
+	Role       string `yaml:"role" json:"role" toml:"role"`
+	Content    string `yaml:"content,omitempty" json:"content,omitempty" toml:"content,omitempty"`
+	Name       string `yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`
+	ToolCallID string `yaml:"toolCallId,omitempty" json:"toolCallId,omitempty" toml:"toolCallId,omitempty"`
+
+	// literal indicates that the content should be used as-is rather than rendered as a template
+	literal bool
+}
+
+// Message represents a single rendered turn in a conversation with a model. Messages containing media hold their
+// content as a list of parts, otherwise the content is held as text.
+type Message struct {
+	Role       string        `json:"role"`
+	Content    string        `json:"content,omitempty"`
+	Parts      []ContentPart `json:"parts,omitempty"`
+	Name       string        `json:"name,omitempty"`
+	ToolCallID string        `json:"toolCallId,omitempty"`
+}
+
+// Text returns the text content of the message, joining the text of each part if the message holds parts.
+func (m Message) Text() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+
+	var text strings.Builder
+	for _, part := range m.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// GetMessages renders the prompt file into the list of messages to send to the model. The history is inserted at
+// the history placeholder, which prompt files using the `prompts` form have immediately before the user prompt.
+// JSON format instructions are added to the first system message if required.
+func (pf *PromptFile) GetMessages(values map[string]interface{}, history []Message) ([]Message, error) {
+	bindings, media, err := pf.parseAndValidateParameters(values)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := pf.messageTemplates()
+	messages := make([]Message, 0, len(templates)+len(history)+1)
+
+	addJsonInstruction := pf.requiresJsonInstruction()
+	if addJsonInstruction && (len(templates) == 0 || templates[0].Role != SystemRole) {
+		messages = append(messages, Message{Role: SystemRole, Content: jsonInstruction})
+		addJsonInstruction = false
+	}
+
+	historyPlaced := false
+	for _, template := range templates {
+		if template.Role == HistoryRole {
+			messages = append(messages, history...)
+			historyPlaced = true
+			continue
+		}
+
+		message := Message{Role: template.Role, Name: template.Name, ToolCallID: template.ToolCallID}
+		if template.literal {
+			message.Content = template.Content
+			messages = append(messages, message)
+			continue
+		}
+
+		content := template.Content
+		if addJsonInstruction && template.Role == SystemRole {
+			content = appendJsonInstruction(content)
+			addJsonInstruction = false
+		}
+
+		prompt, renderErr := pf.renderLiquid(content, bindings)
+		if renderErr != nil {
+			return nil, renderErr
+		}
+
+		if mediaMarkerRegex.MatchString(prompt) {
+			message.Parts = splitContentParts(prompt, media)
+		} else {
+			message.Content = prompt
+		}
+		messages = append(messages, message)
+	}
+
+	if len(history) > 0 && !historyPlaced {
+		return nil, &PromptError{
+			Message: "the prompt file does not contain a history placeholder",
+		}
+	}
+
+	return messages, nil
+}
+
+// messageTemplates returns the message templates for the prompt file. Files using the `prompts` form are mapped onto
+// a system message, the few-shot pairs, a history placeholder, and a user message. Few-shot pairs are inserted
+// after any leading system messages.
+func (pf *PromptFile) messageTemplates() []MessageTemplate {
+	fewShots := make([]MessageTemplate, 0, len(pf.FewShots)*2)
+	for _, fewShot := range pf.FewShots {
+		fewShots = append(fewShots,
+			MessageTemplate{Role: UserRole, Content: fewShot.User, literal: true},
+			MessageTemplate{Role: AssistantRole, Content: fewShot.Response, literal: true},
+		)
+	}
+
+	if len(pf.Messages) > 0 {
+		leadingSystem := 0
+		for leadingSystem < len(pf.Messages) && pf.Messages[leadingSystem].Role == SystemRole {
+			leadingSystem++
+		}
+		templates := slices.Clone(pf.Messages[:leadingSystem])
+		templates = append(templates, fewShots...)
+		return append(templates, pf.Messages[leadingSystem:]...)
+	}
+
+	templates := make([]MessageTemplate, 0, len(fewShots)+3)
+	if len(pf.Prompts.System) > 0 {
+		templates = append(templates, MessageTemplate{Role: SystemRole, Content: pf.Prompts.System})
+	}
+	templates = append(templates, fewShots...)
+	return append(templates,
+		MessageTemplate{Role: HistoryRole},
+		MessageTemplate{Role: UserRole, Content: pf.Prompts.User},
+	)
+}
+
+// systemTemplate returns the template for the system prompt, which is the first system message for files using the
+// `messages` form.
+func (pf *PromptFile) systemTemplate() string {
+	if len(pf.Messages) == 0 {
+		return pf.Prompts.System
+	}
+
+	for _, message := range pf.Messages {
+		if message.Role == SystemRole {
+			return message.Content
+		}
+	}
+	return ""
+}
+
+// userTemplate returns the template for the user prompt, which is the last user message for files using the
+// `messages` form.
+func (pf *PromptFile) userTemplate() string {
+	if len(pf.Messages) == 0 {
+		return pf.Prompts.User
+	}
+
+	for i := len(pf.Messages) - 1; i >= 0; i-- {
+		if pf.Messages[i].Role == UserRole {
+			return pf.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// requiresJsonInstruction returns true if the prompt file requests JSON output but none of its templates mention
+// JSON, in which case an instruction to respond in JSON is added to the system prompt.
+func (pf *PromptFile) requiresJsonInstruction() bool {
+	if pf.Config.OutputFormat != Json {
+		return false
+	}
+
+	templates := []string{pf.Prompts.System, pf.Prompts.User}
+	for _, message := range pf.Messages {
+		templates = append(templates, message.Content)
+	}
+
+	return !slices.ContainsFunc(templates, func(template string) bool {
+		return strings.Contains(strings.ToLower(template), "json")
+	})
+}
+
+// appendJsonInstruction appends the JSON format instruction to a system prompt.
+func appendJsonInstruction(systemPrompt string) string {
+	if len(systemPrompt) == 0 {
+		return jsonInstruction
+	}
+	return systemPrompt + " " + jsonInstruction
+}
+
+// validateMessages checks that a prompt file using the `messages` form does not also use the `prompts` form, that
+// each message has a valid role and content, and that there is a user message or history placeholder.
+func validateMessages(pf *PromptFile) error {
+	if len(pf.Prompts.System) > 0 || len(pf.Prompts.User) > 0 {
+		return &PromptError{
+			Message: "a prompt file cannot contain both prompts and messages",
+		}
+	}
+
+	hasHistory := false
+	hasUser := false
+
+	for i, message := range pf.Messages {
+		if !slices.Contains(validMessageRoles, message.Role) {
+			return &PromptError{
+				Message: fmt.Sprintf("invalid role for message %d: %s", i, message.Role),
+			}
+		}
+
+		switch message.Role {
+		case HistoryRole:
+			if hasHistory {
+				return &PromptError{
+					Message: "a prompt file can only contain one history placeholder",
+				}
+			}
+			hasHistory = true
+			continue
+		case UserRole:
+			hasUser = true
+		}
+
+		if len(message.Content) == 0 {
+			return &PromptError{
+				Message: fmt.Sprintf("no content was provided for message %d", i),
+			}
+		}
+	}
+
+	if !hasUser && !hasHistory {
+		return &PromptError{
+			Message: "no user message or history placeholder was provided in the prompt file",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package dotprompt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewPromptFileFromFile_WithMessages(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/messages.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFile.Messages) != 5 {
+		t.Fatalf("Expected 5 messages, got %d", len(promptFile.Messages))
+	}
+
+	if promptFile.Messages[3].Role != HistoryRole {
+		t.Errorf("Expected fourth message to be the history placeholder, got '%s'", promptFile.Messages[3].Role)
+	}
+}
+
+func TestPromptFile_GetMessages_WithMessagesAndHistory(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/messages.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history := []Message{
+		{Role: UserRole, Content: "My device will not turn on"},
+		{Role: AssistantRole, Content: "Have you tried charging it?"},
+	}
+
+	messages, err := promptFile.GetMessages(map[string]interface{}{"product": "Gizmo", "question": "Yes, it still does not work"}, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Message{
+		{Role: SystemRole, Content: "You are a support agent for Gizmo"},
+		{Role: UserRole, Content: "Where can I find the manual?"},
+		{Role: AssistantRole, Content: "The manual for Gizmo is available on our website"},
+		{Role: UserRole, Content: "My device will not turn on"},
+		{Role: AssistantRole, Content: "Have you tried charging it?"},
+		{Role: UserRole, Content: "Yes, it still does not work"},
+	}
+
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected messages to be %+v, got %+v", expected, messages)
+	}
+}
+
+func TestPromptFile_GetMessages_WithPromptsForm(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/basic-fsp.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history := []Message{{Role: UserRole, Content: "Previous question"}, {Role: AssistantRole, Content: "Previous answer"}}
+
+	messages, err := promptFile.GetMessages(map[string]interface{}{"topic": "social media"}, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRoles := []string{SystemRole, UserRole, AssistantRole, UserRole, AssistantRole, UserRole, AssistantRole, UserRole, AssistantRole, UserRole}
+	roles := make([]string, 0, len(messages))
+	for _, message := range messages {
+		roles = append(roles, message.Role)
+	}
+
+	if !reflect.DeepEqual(roles, expectedRoles) {
+		t.Fatalf("Expected roles to be %v, got %v", expectedRoles, roles)
+	}
+
+	if messages[1].Content != "What is Bluetooth" {
+		t.Errorf("Expected first few-shot to be 'What is Bluetooth', got '%s'", messages[1].Content)
+	}
+
+	if messages[7].Content != "Previous question" {
+		t.Errorf("Expected history to precede the user prompt, got '%s'", messages[7].Content)
+	}
+
+	expectedUser := "Explain the impact of social media on how we engage with technology as a society"
+	if messages[9].Content != expectedUser {
+		t.Errorf("Expected user prompt to be '%s', got '%s'", expectedUser, messages[9].Content)
+	}
+}
+
+func TestPromptFile_GetMessages_WithJsonOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []Message
+	}{
+		{
+			"prompts-without-system",
+			"config:\n  outputFormat: json\nprompts:\n  user: User prompt",
+			[]Message{{Role: SystemRole, Content: jsonInstruction}, {Role: UserRole, Content: "User prompt"}},
+		},
+		{
+			"messages-with-system",
+			"config:\n  outputFormat: json\nmessages:\n  - role: system\n    content: System prompt\n  - role: user\n    content: User prompt",
+			[]Message{{Role: SystemRole, Content: "System prompt " + jsonInstruction}, {Role: UserRole, Content: "User prompt"}},
+		},
+		{
+			"messages-mentioning-json",
+			"config:\n  outputFormat: json\nmessages:\n  - role: user\n    content: Reply in JSON",
+			[]Message{{Role: UserRole, Content: "Reply in JSON"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			promptFile, err := NewPromptFile(test.name, []byte(test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			messages, err := promptFile.GetMessages(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(messages, test.expected) {
+				t.Errorf("Expected messages to be %+v, got %+v", test.expected, messages)
+			}
+		})
+	}
+}
+
+func TestPromptFile_GetMessages_WithToolMessage(t *testing.T) {
+	content := "messages:\n  - role: user\n    content: What is the weather?\n  - role: tool\n    name: get_weather\n    toolCallId: call_1\n    content: '{\"temperature\": {{ temperature }}}'\nconfig:\n  input:\n    parameters:\n      temperature: number"
+	promptFile, err := NewPromptFile("tool-message", []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := promptFile.GetMessages(map[string]interface{}{"temperature": 21}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Message{Role: ToolRole, Name: "get_weather", ToolCallID: "call_1", Content: `{"temperature": 21}`}
+	if !reflect.DeepEqual(messages[1], expected) {
+		t.Errorf("Expected tool message to be %+v, got %+v", expected, messages[1])
+	}
+}
+
+func TestPromptFile_GetMessages_WithHistoryButNoPlaceholder_ReturnsError(t *testing.T) {
+	promptFile, err := NewPromptFile("no-history", []byte("messages:\n  - role: user\n    content: User prompt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = promptFile.GetMessages(nil, []Message{{Role: UserRole, Content: "Hello"}})
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expectedError := "the prompt file does not contain a history placeholder"
+	if promptError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, promptError.Error())
+	}
+}
+
+func TestPromptFile_GetSystemAndUserPrompt_WithMessages(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/messages.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]interface{}{"product": "Gizmo", "question": "Is it waterproof?"}
+
+	systemPrompt, err := promptFile.GetSystemPrompt(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if systemPrompt != "You are a support agent for Gizmo" {
+		t.Errorf("Expected system prompt to be 'You are a support agent for Gizmo', got '%s'", systemPrompt)
+	}
+
+	userPrompt, err := promptFile.GetUserPrompt(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if userPrompt != "Is it waterproof?" {
+		t.Errorf("Expected user prompt to be 'Is it waterproof?', got '%s'", userPrompt)
+	}
+}
+
+func TestNewPromptFile_WithInvalidMessages_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{"prompts-and-messages", "prompts:\n  user: User\nmessages:\n  - role: user\n    content: User", "a prompt file cannot contain both prompts and messages"},
+		{"invalid-role", "messages:\n  - role: narrator\n    content: Once upon a time", "invalid role for message 0: narrator"},
+		{"missing-content", "messages:\n  - role: user", "no content was provided for message 0"},
+		{"duplicate-history", "messages:\n  - role: history\n  - role: history", "a prompt file can only contain one history placeholder"},
+		{"no-user-message", "messages:\n  - role: system\n    content: System", "no user message or history placeholder was provided in the prompt file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewPromptFile(test.name, []byte(test.content))
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestPromptFile_Serialize_WithMessages(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/messages.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialized, err := promptFile.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roundTripped, err := NewPromptFile("round-trip", serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(promptFile.Messages, roundTripped.Messages) {
+		t.Errorf("Expected messages to be %+v, got %+v", promptFile.Messages, roundTripped.Messages)
+	}
+
+	if roundTripped.Prompts != (Prompts{}) {
+		t.Errorf("Expected prompts to be empty, got %+v", roundTripped.Prompts)
+	}
+}
@@ -0,0 +1,140 @@
+package dotprompt
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// modelAliasPrefix is the prefix of a model which refers to a model alias rather than naming a model directly.
+const modelAliasPrefix = "alias:"
+
+// WithModelAliases sets the model aliases of the Manager, mapping alias names such as "fast" or "smart" to the models
+// they refer to. Prompt files refer to an alias with a model of "alias:<name>", which GetPromptFile replaces with the
+// model the alias refers to.
+func WithModelAliases(aliases map[string]string) ManagerOption {
+	return func(m *Manager) {
+		m.modelAliases = maps.Clone(aliases)
+	}
+}
+
+// LoadModelAliases reads model aliases from a file mapping each alias name to the model it refers to, for use with
+// WithModelAliases. Files with a ".toml" extension are read as TOML, and all others as YAML, which includes JSON.
+func LoadModelAliases(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("failed to read model aliases: %v", err),
+		}
+	}
+
+	aliases := make(map[string]string)
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &aliases)
+	} else {
+		err = yaml.Unmarshal(data, &aliases)
+	}
+	if err != nil {
+		return nil, &PromptError{
+			Message: fmt.Sprintf("failed to parse model aliases: %v", err),
+		}
+	}
+
+	return aliases, nil
+}
+
+// ModelAlias returns the name of the model alias the prompt file refers to, and false if the model of the prompt file
+// is not an alias.
+func (pf *PromptFile) ModelAlias() (string, bool) {
+	alias, ok := strings.CutPrefix(pf.Model, modelAliasPrefix)
+	if !ok {
+		return "", false
+	}
+	return alias, true
+}
+
+// ResolveModel returns the model the model alias refers to when the model is of the form "alias:<name>", and the model
+// unchanged otherwise. Returns an error if the alias is not known to the Manager.
+func (m *Manager) ResolveModel(model string) (string, error) {
+	alias, ok := strings.CutPrefix(model, modelAliasPrefix)
+	if !ok {
+		return model, nil
+	}
+
+	resolved, ok := m.modelAliases[alias]
+	if !ok {
+		return "", &PromptError{
+			Message: fmt.Sprintf("unknown model alias: %s", alias),
+		}
+	}
+	return resolved, nil
+}
+
+// ListPromptFilesByModel returns the sorted names of the prompt files managed by the Manager, keyed by the model they
+// use once the active profile and model aliases have been resolved. Prompt files which do not specify a model are
+// listed under an empty string.
+func (m *Manager) ListPromptFilesByModel() (map[string][]string, error) {
+	models := make(map[string][]string)
+	for name := range m.PromptFiles {
+		promptFile, err := m.GetPromptFile(name)
+		if err != nil {
+			return nil, err
+		}
+		models[promptFile.Model] = append(models[promptFile.Model], name)
+	}
+
+	for _, names := range models {
+		slices.Sort(names)
+	}
+
+	return models, nil
+}
+
+// validateModelAliases checks that the model aliases refer to models, rather than to other aliases, and that every
+// alias referred to by the prompt files, including in their overrides, is known.
+func (m *Manager) validateModelAliases() error {
+	for _, alias := range slices.Sorted(maps.Keys(m.modelAliases)) {
+		model := m.modelAliases[alias]
+		if model == "" {
+			return &PromptError{
+				Message: fmt.Sprintf("model alias %s does not refer to a model", alias),
+			}
+		}
+
+		if strings.HasPrefix(model, modelAliasPrefix) {
+			return &PromptError{
+				Message: fmt.Sprintf("model alias %s cannot refer to another alias: %s", alias, model),
+			}
+		}
+	}
+
+	names := m.ListPromptFileNames()
+	slices.Sort(names)
+
+	for _, name := range names {
+		promptFile := m.PromptFiles[name]
+
+		models := []string{promptFile.Model}
+		for _, profile := range promptFile.Profiles() {
+			models = append(models, promptFile.Overrides[profile].Model)
+		}
+
+		for _, model := range models {
+			if _, err := m.ResolveModel(model); err != nil {
+				source := promptFile.Source
+				return &PromptError{
+					Message: fmt.Sprintf("prompt file %s refers to an unknown model alias: %s", name, strings.TrimPrefix(model, modelAliasPrefix)),
+					Source:  &source,
+				}
+			}
+		}
+	}
+
+	return nil
+}
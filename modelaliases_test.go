@@ -0,0 +1,201 @@
+package dotprompt
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func newAliasedPromptFile(t *testing.T, name string, model string) PromptFile {
+	t.Helper()
+
+	promptFile, err := NewBuilder(name).Model(model).User("Hello").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *promptFile
+}
+
+func TestLoadModelAliases(t *testing.T) {
+	expected := map[string]string{"fast": "gpt-4o-mini", "smart": "gpt-4o"}
+
+	for _, path := range []string{"test-data/model-aliases.yaml", "test-data/model-aliases.toml"} {
+		t.Run(path, func(t *testing.T) {
+			aliases, err := LoadModelAliases(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !maps.Equal(aliases, expected) {
+				t.Errorf("Expected aliases %v, got %v", expected, aliases)
+			}
+		})
+	}
+}
+
+func TestLoadModelAliases_WithMissingFile_ReturnsError(t *testing.T) {
+	_, err := LoadModelAliases("test-data/does-not-exist.yaml")
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+}
+
+func TestPromptFile_ModelAlias(t *testing.T) {
+	tests := []struct {
+		model         string
+		expectedAlias string
+		expectedOk    bool
+	}{
+		{"alias:fast", "fast", true},
+		{"gpt-4o", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.model, func(t *testing.T) {
+			promptFile := PromptFile{Model: test.model}
+			alias, ok := promptFile.ModelAlias()
+			if alias != test.expectedAlias || ok != test.expectedOk {
+				t.Errorf("Expected alias '%s' and %v, got '%s' and %v", test.expectedAlias, test.expectedOk, alias, ok)
+			}
+		})
+	}
+}
+
+func TestGetPromptFile_WithModelAliases(t *testing.T) {
+	loader := &MockLoader{
+		PromptFiles: []PromptFile{
+			newAliasedPromptFile(t, "summarise", "alias:fast"),
+			newAliasedPromptFile(t, "research", "alias:smart"),
+			newAliasedPromptFile(t, "legacy", "gpt-3.5-turbo"),
+		},
+	}
+
+	mgr, err := NewManagerFromLoader(loader, WithModelAliases(map[string]string{"fast": "gpt-4o-mini", "smart": "gpt-4o"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		expectedModel    string
+		expectSerializes bool
+	}{
+		{"summarise", "gpt-4o-mini", false},
+		{"research", "gpt-4o", false},
+		{"legacy", "gpt-3.5-turbo", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			promptFile, err := mgr.GetPromptFile(test.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if promptFile.Model != test.expectedModel {
+				t.Errorf("Expected model '%s', got '%s'", test.expectedModel, promptFile.Model)
+			}
+
+			// Prompt files with a resolved alias cannot be serialized, as the model would replace the alias
+			if _, err = promptFile.Serialize(); (err == nil) != test.expectSerializes {
+				t.Errorf("Expected serializes to be %v, got error %v", test.expectSerializes, err)
+			}
+		})
+	}
+
+	if mgr.PromptFiles["summarise"].Model != "alias:fast" {
+		t.Error("Expected the stored prompt file to keep its model alias")
+	}
+}
+
+func TestGetPromptFile_WithModelAliasInProfile(t *testing.T) {
+	promptFile := newAliasedPromptFile(t, "summarise", "alias:fast")
+	promptFile.Overrides = map[string]ProfileOverride{"prod": {Model: "alias:smart"}}
+
+	mgr, err := NewManagerFromLoader(
+		&MockLoader{PromptFiles: []PromptFile{promptFile}},
+		WithProfile("prod"),
+		WithModelAliases(map[string]string{"fast": "gpt-4o-mini", "smart": "gpt-4o"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := mgr.GetPromptFile("summarise")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resolved.Model != "gpt-4o" {
+		t.Errorf("Expected model 'gpt-4o', got '%s'", resolved.Model)
+	}
+}
+
+func TestNewManagerFromLoader_WithInvalidModelAliases_ReturnsError(t *testing.T) {
+	withOverride := newAliasedPromptFile(t, "research", "gpt-4o")
+	withOverride.Overrides = map[string]ProfileOverride{"dev": {Model: "alias:cheap"}}
+
+	tests := []struct {
+		name          string
+		promptFile    PromptFile
+		aliases       map[string]string
+		expectedError string
+	}{
+		{"unknown-alias", newAliasedPromptFile(t, "summarise", "alias:quick"), map[string]string{"fast": "gpt-4o-mini"}, "prompt file summarise refers to an unknown model alias: quick"},
+		{"no-aliases", newAliasedPromptFile(t, "summarise", "alias:fast"), nil, "prompt file summarise refers to an unknown model alias: fast"},
+		{"unknown-override-alias", withOverride, map[string]string{"fast": "gpt-4o-mini"}, "prompt file research refers to an unknown model alias: cheap"},
+		{"empty-alias", newAliasedPromptFile(t, "summarise", "gpt-4o"), map[string]string{"fast": ""}, "model alias fast does not refer to a model"},
+		{"nested-alias", newAliasedPromptFile(t, "summarise", "gpt-4o"), map[string]string{"fast": "alias:smart"}, "model alias fast cannot refer to another alias: alias:smart"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			loader := &MockLoader{PromptFiles: []PromptFile{test.promptFile}}
+			_, err := NewManagerFromLoader(loader, WithModelAliases(test.aliases))
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestManager_ListPromptFilesByModel(t *testing.T) {
+	loader := &MockLoader{
+		PromptFiles: []PromptFile{
+			newAliasedPromptFile(t, "summarise", "alias:fast"),
+			newAliasedPromptFile(t, "classify", "gpt-4o-mini"),
+			newAliasedPromptFile(t, "research", "alias:smart"),
+			newAliasedPromptFile(t, "unset", ""),
+		},
+	}
+
+	mgr, err := NewManagerFromLoader(loader, WithModelAliases(map[string]string{"fast": "gpt-4o-mini", "smart": "gpt-4o"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models, err := mgr.ListPromptFilesByModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"gpt-4o-mini": {"classify", "summarise"},
+		"gpt-4o":      {"research"},
+		"":            {"unset"},
+	}
+	if !reflect.DeepEqual(models, expected) {
+		t.Errorf("Expected models %v, got %v", expected, models)
+	}
+}
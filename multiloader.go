@@ -0,0 +1,124 @@
+package dotprompt
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// LoaderSource represents a named Loader used as one of the sources of a MultiLoader.
+type LoaderSource struct {
+	Name   string
+	Loader Loader
+}
+
+// MultiLoader combines several loaders into one, in priority order. When more than one source contains a prompt file
+// with the same name, the prompt file from the source listed first is used and the others are ignored, so that, for
+// example, files on disk can override prompt files embedded in the application.
+type MultiLoader struct {
+	sources []LoaderSource
+	mu      sync.Mutex
+	origins map[string]string
+}
+
+// NewMultiLoader creates a new MultiLoader from the sources, listed from the highest priority to the lowest.
+func NewMultiLoader(sources ...LoaderSource) (*MultiLoader, error) {
+	if len(sources) == 0 {
+		return nil, &PromptError{
+			Message: "at least one loader source must be provided",
+		}
+	}
+
+	names := make(map[string]bool, len(sources))
+	for i, source := range sources {
+		if source.Loader == nil {
+			return nil, &PromptError{
+				Message: fmt.Sprintf("loader cannot be nil for source %d", i),
+			}
+		}
+
+		if strings.TrimSpace(source.Name) == "" {
+			return nil, &PromptError{
+				Message: fmt.Sprintf("no name was provided for source %d", i),
+			}
+		}
+
+		if names[source.Name] {
+			return nil, &PromptError{
+				Message: fmt.Sprintf("duplicate loader source name: %s", source.Name),
+			}
+		}
+		names[source.Name] = true
+	}
+
+	return &MultiLoader{
+		sources: sources,
+		origins: make(map[string]string),
+	}, nil
+}
+
+// Load loads the prompt files from every source, in priority order, and returns a slice of PromptFile containing the
+// highest priority prompt file for each name. Returns an error if any source fails to load, or if a single source
+// contains more than one prompt file with the same name.
+func (m *MultiLoader) Load() ([]PromptFile, error) {
+	return m.LoadContext(context.Background())
+}
+
+// LoadContext loads the prompt files from every source in the same way as Load, passing the context to each source.
+// Sources which do not implement ContextLoader are adapted using NewContextLoader.
+func (m *MultiLoader) LoadContext(ctx context.Context) ([]PromptFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	promptFiles := make([]PromptFile, 0)
+	origins := make(map[string]string)
+
+	for _, source := range m.sources {
+		loaded, err := NewContextLoader(source.Loader).LoadContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		seen := make(map[string]bool, len(loaded))
+		for _, promptFile := range loaded {
+			if seen[promptFile.Name] {
+				return nil, &PromptError{
+					Message: fmt.Sprintf("duplicate prompt file name: %s in source %s", promptFile.Name, source.Name),
+				}
+			}
+			seen[promptFile.Name] = true
+
+			if _, ok := origins[promptFile.Name]; ok {
+				continue
+			}
+
+			origins[promptFile.Name] = source.Name
+			promptFiles = append(promptFiles, promptFile)
+		}
+	}
+
+	m.origins = origins
+
+	return promptFiles, nil
+}
+
+// Source returns the name of the source which the named prompt file was loaded from by the most recent call to Load,
+// and a boolean indicating whether the prompt file was found.
+func (m *MultiLoader) Source(name string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source, ok := m.origins[name]
+	return source, ok
+}
+
+// Sources returns a map from the name of each prompt file loaded by the most recent call to Load to the name of the
+// source it was loaded from.
+func (m *MultiLoader) Sources() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return maps.Clone(m.origins)
+}
@@ -0,0 +1,148 @@
+package dotprompt
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestMultiLoader_Load_UsesHighestPriority(t *testing.T) {
+	overrides := &MockLoader{
+		PromptFiles: []PromptFile{{Name: "basic", Model: "override-model"}},
+	}
+	base := &MockLoader{
+		PromptFiles: []PromptFile{{Name: "basic", Model: "base-model"}, {Name: "other", Model: "base-model"}},
+	}
+
+	loader, err := NewMultiLoader(
+		LoaderSource{Name: "overrides", Loader: overrides},
+		LoaderSource{Name: "base", Loader: base},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewManagerFromLoader(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	basic, err := manager.GetPromptFile("basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if basic.Model != "override-model" {
+		t.Errorf("Expected model 'override-model', got '%s'", basic.Model)
+	}
+
+	if source, ok := loader.Source("basic"); !ok || source != "overrides" {
+		t.Errorf("Expected source 'overrides', got '%s'", source)
+	}
+
+	expected := map[string]string{"basic": "overrides", "other": "base"}
+	if sources := loader.Sources(); !maps.Equal(sources, expected) {
+		t.Errorf("Expected sources %v, got %v", expected, sources)
+	}
+
+	if _, ok := loader.Source("missing"); ok {
+		t.Error("Expected missing prompt file to have no source")
+	}
+}
+
+func TestMultiLoader_Load_WithFileStoreOverFSStore(t *testing.T) {
+	fileStore, err := NewFileStoreFromPath("file-store-tests/sub-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := NewMultiLoader(
+		LoaderSource{Name: "disk", Loader: fileStore},
+		LoaderSource{Name: "embedded", Loader: NewFSStore(validFs)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewManagerFromLoader(loader); err != nil {
+		t.Fatal(err)
+	}
+
+	if source, _ := loader.Source("another-example-with-name"); source != "disk" {
+		t.Errorf("Expected source 'disk', got '%s'", source)
+	}
+}
+
+func TestMultiLoader_Load_WithErrors(t *testing.T) {
+	loadErr := errors.New("load failed")
+
+	tests := []struct {
+		name          string
+		loader        *MockLoader
+		expectedError string
+	}{
+		{"loader-error", &MockLoader{Err: loadErr}, "load failed"},
+		{
+			"duplicate-in-source",
+			&MockLoader{PromptFiles: []PromptFile{{Name: "basic"}, {Name: "basic"}}},
+			"duplicate prompt file name: basic in source second",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			loader, err := NewMultiLoader(
+				LoaderSource{Name: "first", Loader: &MockLoader{PromptFiles: []PromptFile{{Name: "basic"}}}},
+				LoaderSource{Name: "second", Loader: test.loader},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = loader.Load()
+			if err == nil {
+				t.Fatal("Expected error, got none")
+			}
+
+			if err.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestNewMultiLoader_WithInvalidSources(t *testing.T) {
+	loader := &MockLoader{}
+
+	tests := []struct {
+		name          string
+		sources       []LoaderSource
+		expectedError string
+	}{
+		{"no-sources", nil, "at least one loader source must be provided"},
+		{"nil-loader", []LoaderSource{{Name: "base"}}, "loader cannot be nil for source 0"},
+		{"empty-name", []LoaderSource{{Name: " ", Loader: loader}}, "no name was provided for source 0"},
+		{
+			"duplicate-name",
+			[]LoaderSource{{Name: "base", Loader: loader}, {Name: "base", Loader: loader}},
+			"duplicate loader source name: base",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewMultiLoader(test.sources...)
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package dotprompt
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// modelParameter describes a model parameter which has a field of its own.
+type modelParameter struct {
+	field        string
+	expectedType string
+}
+
+// knownModelParameters holds the model parameters which have a field of their own, keyed by the name they are given in
+// a model request. Values for these parameters in Extra are checked against the expected type, and are rejected if the
+// field is also set.
+var knownModelParameters = map[string]modelParameter{
+	"temperature":       {"temperature", "a number"},
+	"max_tokens":        {"maxTokens", "an integer"},
+	"top_p":             {"topP", "a number"},
+	"top_k":             {"topK", "an integer"},
+	"stop":              {"stop", "a list of strings"},
+	"seed":              {"seed", "an integer"},
+	"presence_penalty":  {"presencePenalty", "a number"},
+	"frequency_penalty": {"frequencyPenalty", "a number"},
+	"reasoning_effort":  {"reasoningEffort", "a string"},
+}
+
+// ModelParameters returns the model parameters set in the prompt file, keyed by the snake case names commonly used in
+// model requests, such as "top_p" and "max_tokens". Values in Extra are included as-is.
+func (pf *PromptFile) ModelParameters() map[string]interface{} {
+	return pf.providerParameters(nil, nil)
+}
+
+// providerParameters returns the model parameters in the same way as ModelParameters, with the parameters a provider
+// does not support removed and the parameters it names differently renamed. Values in Extra are always included.
+func (pf *PromptFile) providerParameters(unsupported []string, renamed map[string]string) map[string]interface{} {
+	parameters := fieldModelParameters(pf.Config)
+
+	for _, name := range unsupported {
+		delete(parameters, name)
+	}
+
+	for name, providerName := range renamed {
+		if value, ok := parameters[name]; ok {
+			delete(parameters, name)
+			parameters[providerName] = value
+		}
+	}
+
+	maps.Copy(parameters, pf.Config.Extra)
+
+	return parameters
+}
+
+// additionalModelParameters returns the model parameters set in the prompt file other than the temperature, max
+// tokens, and Extra.
+func (pf *PromptFile) additionalModelParameters() map[string]interface{} {
+	parameters := fieldModelParameters(pf.Config)
+	delete(parameters, "temperature")
+	delete(parameters, "max_tokens")
+	return parameters
+}
+
+// fieldModelParameters returns the model parameters which are set using their own field in the config, keyed by the
+// name they are given in a model request.
+func fieldModelParameters(config PromptConfig) map[string]interface{} {
+	parameters := make(map[string]interface{})
+	if config.Temperature != nil {
+		parameters["temperature"] = *config.Temperature
+	}
+	if config.MaxTokens != nil {
+		parameters["max_tokens"] = *config.MaxTokens
+	}
+	if config.TopP != nil {
+		parameters["top_p"] = *config.TopP
+	}
+	if config.TopK != nil {
+		parameters["top_k"] = *config.TopK
+	}
+	if len(config.Stop) > 0 {
+		parameters["stop"] = slices.Clone(config.Stop)
+	}
+	if config.Seed != nil {
+		parameters["seed"] = *config.Seed
+	}
+	if config.PresencePenalty != nil {
+		parameters["presence_penalty"] = *config.PresencePenalty
+	}
+	if config.FrequencyPenalty != nil {
+		parameters["frequency_penalty"] = *config.FrequencyPenalty
+	}
+	if config.ReasoningEffort != "" {
+		parameters["reasoning_effort"] = config.ReasoningEffort
+	}
+	return parameters
+}
+
+// validateModelParameters checks that the model parameters of the config are within their valid ranges, and that any
+// values in Extra for parameters with a field of their own have the expected type and do not duplicate the field.
+func validateModelParameters(config PromptConfig) error {
+	if config.TopP != nil && (*config.TopP < 0 || *config.TopP > 1) {
+		return &PromptError{
+			Message: fmt.Sprintf("invalid value for topP: %v, must be between 0 and 1", *config.TopP),
+		}
+	}
+
+	if config.TopK != nil && *config.TopK < 1 {
+		return &PromptError{
+			Message: fmt.Sprintf("invalid value for topK: %d, must be at least 1", *config.TopK),
+		}
+	}
+
+	penalties := []struct {
+		name  string
+		value *float32
+	}{
+		{"presencePenalty", config.PresencePenalty},
+		{"frequencyPenalty", config.FrequencyPenalty},
+	}
+	for _, penalty := range penalties {
+		if penalty.value != nil && (*penalty.value < -2 || *penalty.value > 2) {
+			return &PromptError{
+				Message: fmt.Sprintf("invalid value for %s: %v, must be between -2 and 2", penalty.name, *penalty.value),
+			}
+		}
+	}
+
+	// The accepted reasoning efforts vary between providers and models, so any value is passed through unless blank
+	if config.ReasoningEffort != "" && strings.TrimSpace(config.ReasoningEffort) == "" {
+		return &PromptError{
+			Message: "invalid reasoning effort: must not be blank",
+		}
+	}
+
+	fields := fieldModelParameters(config)
+	for _, key := range slices.Sorted(maps.Keys(config.Extra)) {
+		parameter, ok := knownModelParameters[key]
+		if !ok {
+			continue
+		}
+
+		if _, set := fields[key]; set {
+			return &PromptError{
+				Message: fmt.Sprintf("extra parameter %s duplicates %s", key, parameter.field),
+			}
+		}
+
+		if !isModelParameterType(config.Extra[key], parameter.expectedType) {
+			return &PromptError{
+				Message: fmt.Sprintf("invalid value for extra parameter %s: expected %s", key, parameter.expectedType),
+			}
+		}
+	}
+
+	return nil
+}
+
+// isModelParameterType returns true if the value is of the expected model parameter type. Whole numbers are accepted
+// as integers regardless of their type, as JSON decodes all numbers as float64.
+func isModelParameterType(value interface{}, expectedType string) bool {
+	switch expectedType {
+	case "a number":
+		return isNumeric(value)
+	case "an integer":
+		switch typedValue := value.(type) {
+		case float32:
+			return typedValue == float32(math.Trunc(float64(typedValue)))
+		case float64:
+			return typedValue == math.Trunc(typedValue)
+		default:
+			return isNumeric(value)
+		}
+	case "a list of strings":
+		switch typedValue := value.(type) {
+		case []string:
+			return true
+		case []interface{}:
+			return !slices.ContainsFunc(typedValue, func(item interface{}) bool {
+				_, isString := item.(string)
+				return !isString
+			})
+		default:
+			return false
+		}
+	default:
+		_, isString := value.(string)
+		return isString
+	}
+}
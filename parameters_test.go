@@ -0,0 +1,166 @@
+package dotprompt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewPromptFileFromFile_WithModelParameters(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/model-parameters.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"temperature":       float32(0.5),
+		"max_tokens":        200,
+		"top_p":             float32(0.9),
+		"top_k":             40,
+		"stop":              []string{"###", "END"},
+		"seed":              42,
+		"presence_penalty":  float32(0.5),
+		"frequency_penalty": float32(-0.5),
+		"reasoning_effort":  "high",
+		"logprobs":          true,
+		"service_tier":      "flex",
+	}
+
+	if parameters := promptFile.ModelParameters(); !reflect.DeepEqual(parameters, expected) {
+		t.Errorf("Expected model parameters %v, got %v", expected, parameters)
+	}
+}
+
+func TestPromptFile_ModelParameters_WithDuplicateExtra_ReturnsError(t *testing.T) {
+	_, err := NewBuilder("override").
+		Temperature(0.5).
+		TopK(10).
+		Extra("top_k", 20).
+		User("Hello").
+		Build()
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expectedError := "extra parameter top_k duplicates topK"
+	if promptError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, promptError.Error())
+	}
+}
+
+func TestPromptFile_ModelParameters_WithCustomReasoningEffort(t *testing.T) {
+	promptFile, err := NewPromptFile("effort", []byte("config:\n  reasoningEffort: xhigh\n"+minimalPrompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{"reasoning_effort": "xhigh"}
+	if parameters := promptFile.ModelParameters(); !reflect.DeepEqual(parameters, expected) {
+		t.Errorf("Expected model parameters %v, got %v", expected, parameters)
+	}
+}
+
+func TestPromptFile_ModelParameters_WithNoParameters(t *testing.T) {
+	promptFile, err := NewPromptFile("empty", []byte(minimalPrompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parameters := promptFile.ModelParameters(); len(parameters) != 0 {
+		t.Errorf("Expected no model parameters, got %v", parameters)
+	}
+}
+
+func TestNewPromptFile_WithInvalidModelParameters_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{"top-p", "topP: 1.5", "invalid value for topP: 1.5, must be between 0 and 1"},
+		{"top-k", "topK: 0", "invalid value for topK: 0, must be at least 1"},
+		{"presence-penalty", "presencePenalty: 2.5", "invalid value for presencePenalty: 2.5, must be between -2 and 2"},
+		{"frequency-penalty", "frequencyPenalty: -3", "invalid value for frequencyPenalty: -3, must be between -2 and 2"},
+		{"reasoning-effort", "reasoningEffort: ' '", "invalid reasoning effort: must not be blank"},
+		{"extra-number", "extra:\n    top_p: high", "invalid value for extra parameter top_p: expected a number"},
+		{"extra-integer", "extra:\n    seed: 1.5", "invalid value for extra parameter seed: expected an integer"},
+		{"extra-stop", "extra:\n    stop: [1, 2]", "invalid value for extra parameter stop: expected a list of strings"},
+		{"extra-string", "extra:\n    reasoning_effort: 3", "invalid value for extra parameter reasoning_effort: expected a string"},
+		{"extra-duplicate", "seed: 1\n  extra:\n    seed: 2", "extra parameter seed duplicates seed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			data := "config:\n  " + test.config + "\n" + minimalPrompt
+			_, err := NewPromptFile(test.name, []byte(data))
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestNewPromptFile_WithValidExtraParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format FileFormat
+	}{
+		{"yaml", "config:\n  extra:\n    seed: 7\n    stop: [END]\n    custom: {nested: true}\n" + minimalPrompt, NativeFormat},
+		{"json", `{"config": {"extra": {"seed": 7, "max_tokens": 10}}, "prompts": {"user": "Hello"}}`, JSONFormat},
+		{"toml", "[config.extra]\nseed = 7\nstop = [\"END\"]\n\n[prompts]\nuser = \"Hello\"\n", TOMLFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			promptFile, err := NewPromptFileWithFormat(test.name, []byte(test.data), test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := promptFile.ModelParameters()["seed"]; !ok {
+				t.Errorf("Expected the seed to be forwarded, got %v", promptFile.ModelParameters())
+			}
+		})
+	}
+}
+
+func TestPromptFile_CacheKey_IncludesModelParameters(t *testing.T) {
+	base, err := NewPromptFile("cache", []byte(minimalPrompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withExtra, err := NewPromptFile("cache", []byte("config:\n  extra:\n    service_tier: flex\n"+minimalPrompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withSeed, err := NewPromptFile("cache", []byte("config:\n  seed: 1\n"+minimalPrompt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make(map[string]bool)
+	for _, promptFile := range []*PromptFile{base, withExtra, withSeed} {
+		key, keyErr := promptFile.CacheKey(nil)
+		if keyErr != nil {
+			t.Fatal(keyErr)
+		}
+		keys[key] = true
+	}
+
+	if len(keys) != 3 {
+		t.Errorf("Expected the model parameters to change the cache key, got %d distinct keys", len(keys))
+	}
+}
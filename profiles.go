@@ -0,0 +1,126 @@
+package dotprompt
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ProfileOverride holds the model and configuration values used in place of those of a prompt file when a profile,
+// such as "dev" or "prod", is active. Values which are not set are left unchanged.
+type ProfileOverride struct {
+	Model  string         `yaml:"model,omitempty" json:"model,omitempty" toml:"model,omitempty"`
+	Config ConfigOverride `yaml:"config,omitempty" json:"config,omitempty" toml:"config,omitempty"`
+}
+
+// ConfigOverride holds the configuration values used in place of those of a prompt file when a profile is active.
+// Values in Extra are merged with those of the prompt file rather than replacing them.
+type ConfigOverride struct {
+	Temperature      *float32               `yaml:"temperature,omitempty" json:"temperature,omitempty" toml:"temperature,omitempty"`
+	MaxTokens        *int                   `yaml:"maxTokens,omitempty" json:"maxTokens,omitempty" toml:"maxTokens,omitempty"`
+	TopP             *float32               `yaml:"topP,omitempty" json:"topP,omitempty" toml:"topP,omitempty"`
+	TopK             *int                   `yaml:"topK,omitempty" json:"topK,omitempty" toml:"topK,omitempty"`
+	Stop             []string               `yaml:"stop,omitempty" json:"stop,omitempty" toml:"stop,omitempty"`
+	Seed             *int                   `yaml:"seed,omitempty" json:"seed,omitempty" toml:"seed,omitempty"`
+	PresencePenalty  *float32               `yaml:"presencePenalty,omitempty" json:"presencePenalty,omitempty" toml:"presencePenalty,omitempty"`
+	FrequencyPenalty *float32               `yaml:"frequencyPenalty,omitempty" json:"frequencyPenalty,omitempty" toml:"frequencyPenalty,omitempty"`
+	ReasoningEffort  string                 `yaml:"reasoningEffort,omitempty" json:"reasoningEffort,omitempty" toml:"reasoningEffort,omitempty"`
+	Extra            map[string]interface{} `yaml:"extra,omitempty" json:"extra,omitempty" toml:"extra,omitempty"`
+	OutputFormat     *OutputFormat          `yaml:"outputFormat,omitempty" json:"outputFormat,omitempty" toml:"outputFormat,omitempty"`
+	Cache            *bool                  `yaml:"cache,omitempty" json:"cache,omitempty" toml:"cache,omitempty"`
+}
+
+// Profiles returns the sorted names of the profiles which the prompt file has overrides for.
+func (pf *PromptFile) Profiles() []string {
+	profiles := slices.Collect(maps.Keys(pf.Overrides))
+	slices.Sort(profiles)
+	return profiles
+}
+
+// ResolveProfile returns a copy of the prompt file with the overrides of the profile applied. The copy is the same as
+// the prompt file if it has no overrides for the profile, so that only the prompt files which differ between profiles
+// need to define them. A copy with overrides applied is only for rendering, and returns an error when serialized.
+func (pf *PromptFile) ResolveProfile(profile string) PromptFile {
+	resolved := *pf
+
+	override, ok := pf.Overrides[profile]
+	if !ok {
+		return resolved
+	}
+
+	if override.Model != "" {
+		resolved.Model = override.Model
+	}
+	resolved.Config = override.Config.apply(pf.Config)
+	resolved.resolved = true
+
+	return resolved
+}
+
+// apply returns a copy of the config with the values set in the override replacing its own. The values are copied, so
+// that changes to the returned config do not change the override.
+func (co ConfigOverride) apply(config PromptConfig) PromptConfig {
+	if co.Temperature != nil {
+		config.Temperature = clonePointer(co.Temperature)
+	}
+	if co.MaxTokens != nil {
+		config.MaxTokens = clonePointer(co.MaxTokens)
+	}
+	if co.TopP != nil {
+		config.TopP = clonePointer(co.TopP)
+	}
+	if co.TopK != nil {
+		config.TopK = clonePointer(co.TopK)
+	}
+	if len(co.Stop) > 0 {
+		config.Stop = slices.Clone(co.Stop)
+	}
+	if co.Seed != nil {
+		config.Seed = clonePointer(co.Seed)
+	}
+	if co.PresencePenalty != nil {
+		config.PresencePenalty = clonePointer(co.PresencePenalty)
+	}
+	if co.FrequencyPenalty != nil {
+		config.FrequencyPenalty = clonePointer(co.FrequencyPenalty)
+	}
+	if co.ReasoningEffort != "" {
+		config.ReasoningEffort = co.ReasoningEffort
+	}
+	if len(co.Extra) > 0 {
+		extra := maps.Clone(config.Extra)
+		if extra == nil {
+			extra = make(map[string]interface{}, len(co.Extra))
+		}
+		maps.Copy(extra, co.Extra)
+		config.Extra = extra
+	}
+	if co.OutputFormat != nil {
+		config.OutputFormat = *co.OutputFormat
+	}
+	if co.Cache != nil {
+		config.Cache = clonePointer(co.Cache)
+	}
+	return config
+}
+
+// validateOverrides checks that the model parameters of the prompt file are valid with the overrides of each profile
+// applied.
+func validateOverrides(promptFile *PromptFile) error {
+	for _, profile := range promptFile.Profiles() {
+		if profile == "" {
+			return &PromptError{
+				Message: "overrides cannot be defined for an empty profile name",
+			}
+		}
+
+		resolved := promptFile.ResolveProfile(profile)
+		if err := validateModelParameters(resolved.Config); err != nil {
+			return &PromptError{
+				Message: fmt.Sprintf("invalid overrides for profile %s: %v", profile, err),
+			}
+		}
+	}
+
+	return nil
+}
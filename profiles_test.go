@@ -0,0 +1,135 @@
+package dotprompt
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPromptFile_ResolveProfile(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-overrides.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile              string
+		expectedModel        string
+		expectedTemperature  float32
+		expectedMaxTokens    int
+		expectedOutputFormat OutputFormat
+		expectedExtra        map[string]interface{}
+	}{
+		{"", "gpt-4o-mini", 0.7, 500, Text, map[string]interface{}{"service_tier": "flex"}},
+		{"dev", "gpt-4o-mini", 0.7, 100, Text, map[string]interface{}{"service_tier": "flex"}},
+		{"prod", "gpt-4o", 0.2, 1000, Json, map[string]interface{}{"service_tier": "priority", "seed_label": "prod"}},
+		{"staging", "gpt-4o-mini", 0.7, 500, Text, map[string]interface{}{"service_tier": "flex"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			resolved := promptFile.ResolveProfile(test.profile)
+
+			if resolved.Model != test.expectedModel {
+				t.Errorf("Expected model '%s', got '%s'", test.expectedModel, resolved.Model)
+			}
+
+			if *resolved.Config.Temperature != test.expectedTemperature {
+				t.Errorf("Expected temperature %v, got %v", test.expectedTemperature, *resolved.Config.Temperature)
+			}
+
+			if *resolved.Config.MaxTokens != test.expectedMaxTokens {
+				t.Errorf("Expected max tokens %d, got %d", test.expectedMaxTokens, *resolved.Config.MaxTokens)
+			}
+
+			if resolved.Config.OutputFormat != test.expectedOutputFormat {
+				t.Errorf("Expected output format %v, got %v", test.expectedOutputFormat, resolved.Config.OutputFormat)
+			}
+
+			if !reflect.DeepEqual(resolved.Config.Extra, test.expectedExtra) {
+				t.Errorf("Expected extra %v, got %v", test.expectedExtra, resolved.Config.Extra)
+			}
+		})
+	}
+
+	if *promptFile.Config.MaxTokens != 500 || promptFile.Config.Extra["service_tier"] != "flex" {
+		t.Error("Expected resolving a profile to leave the prompt file unchanged")
+	}
+}
+
+func TestPromptFile_ResolveProfile_DoesNotShareOverrideValues(t *testing.T) {
+	temperature := float32(0.2)
+	promptFile, err := NewBuilder("shared").
+		User("Hello").
+		Override("prod", ProfileOverride{Config: ConfigOverride{Temperature: &temperature, Stop: []string{"END"}}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := promptFile.ResolveProfile("prod")
+	*resolved.Config.Temperature = 0.9
+	resolved.Config.Stop[0] = "STOP"
+
+	override := promptFile.Overrides["prod"].Config
+	if *override.Temperature != 0.2 {
+		t.Errorf("Expected override temperature to be unchanged, got %v", *override.Temperature)
+	}
+
+	if override.Stop[0] != "END" {
+		t.Errorf("Expected override stop to be unchanged, got %v", override.Stop)
+	}
+}
+
+func TestPromptFile_Profiles(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-overrides.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"dev", "prod"}
+	if profiles := promptFile.Profiles(); !slices.Equal(profiles, expected) {
+		t.Errorf("Expected profiles %v, got %v", expected, profiles)
+	}
+}
+
+func TestNewPromptFile_WithInvalidOverrides_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		overrides     string
+		expectedError string
+	}{
+		{
+			"top-p",
+			"overrides:\n  prod:\n    config:\n      topP: 2\n",
+			"invalid overrides for profile prod: invalid value for topP: 2, must be between 0 and 1",
+		},
+		{
+			"extra",
+			"overrides:\n  dev:\n    config:\n      extra:\n        seed: high\n",
+			"invalid overrides for profile dev: invalid value for extra parameter seed: expected an integer",
+		},
+		{
+			"empty-profile",
+			"overrides:\n  \"\":\n    model: gpt-4o\n",
+			"overrides cannot be defined for an empty profile name",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewPromptFile(test.name, []byte(minimalPrompt+test.overrides))
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
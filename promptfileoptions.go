@@ -0,0 +1,57 @@
+package dotprompt
+
+// PromptFileOption represents an option used when creating a PromptFile.
+type PromptFileOption func(*promptFileOptions)
+
+// promptFileOptions holds the options used when creating a PromptFile.
+type promptFileOptions struct {
+	strict    bool
+	edit      bool
+	coerce    bool
+	mediaRoot string
+}
+
+// newPromptFileOptions returns the prompt file options with the provided options applied to the defaults.
+func newPromptFileOptions(options []PromptFileOption) promptFileOptions {
+	opts := promptFileOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// WithStrict rejects prompt files containing fields which are not part of the prompt file format, such as misspelled
+// keys, which are otherwise ignored. The error lists the path and line number of every unknown field, although line
+// numbers are not available for prompt files in the TOML format.
+func WithStrict() PromptFileOption {
+	return func(o *promptFileOptions) {
+		o.strict = true
+	}
+}
+
+// WithEditMode retains the YAML document of prompt files in the native format, so that Serialize and ToFile apply any
+// changes made to the PromptFile to the original document instead of encoding the PromptFile again. Comments, key
+// order, and the style of values such as `|-` blocks are preserved for everything which has not changed.
+func WithEditMode() PromptFileOption {
+	return func(o *promptFileOptions) {
+		o.edit = true
+	}
+}
+
+// WithCoercion converts loosely typed parameter values to their declared type in the same way as setting
+// `input.coerce: true` in the prompt file, so that values such as the string "42" are accepted for a number parameter.
+// Unsigned integers too large for an int64 are converted to a float64, and only scalar values are converted to strings.
+func WithCoercion() PromptFileOption {
+	return func(o *promptFileOptions) {
+		o.coerce = true
+	}
+}
+
+// WithMediaRoot allows image and file parameters to be given as the path of a file within the root directory. Paths
+// are relative to the root, and paths which leave it, including through symbolic links, are rejected. Without this
+// option only byte slices and data URIs are accepted, so that untrusted values cannot read files from the host.
+func WithMediaRoot(root string) PromptFileOption {
+	return func(o *promptFileOptions) {
+		o.mediaRoot = root
+	}
+}
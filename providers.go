@@ -0,0 +1,147 @@
+package dotprompt
+
+import "encoding/base64"
+
+// OpenAITool represents a tool definition in the format expected by the `tools` field of an OpenAI chat completions
+// request.
+type OpenAITool struct {
+	Type     string         `json:"type"`
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction represents the function described by an OpenAITool.
+type OpenAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// AnthropicTool represents a tool definition in the format expected by the `tools` field of an Anthropic messages
+// request.
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// OpenAITools returns the tools declared in the prompt file in the OpenAI request format.
+func (pf *PromptFile) OpenAITools() []OpenAITool {
+	tools := make([]OpenAITool, 0, len(pf.Tools))
+	for _, tool := range pf.Tools {
+		tools = append(tools, OpenAITool{
+			Type: "function",
+			Function: OpenAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	return tools
+}
+
+// AnthropicTools returns the tools declared in the prompt file in the Anthropic request format. Anthropic requires an
+// input schema, so tools without parameters are given an empty object schema.
+func (pf *PromptFile) AnthropicTools() []AnthropicTool {
+	tools := make([]AnthropicTool, 0, len(pf.Tools))
+	for _, tool := range pf.Tools {
+		inputSchema := tool.Parameters
+		if inputSchema == nil {
+			inputSchema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tools = append(tools, AnthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: inputSchema,
+		})
+	}
+	return tools
+}
+
+// OpenAIParameters returns the model parameters of the prompt file named as in an OpenAI chat completions request.
+// Top K is not supported by OpenAI and is omitted unless it is set in Extra.
+func (pf *PromptFile) OpenAIParameters() map[string]interface{} {
+	return pf.providerParameters([]string{"top_k"}, nil)
+}
+
+// AnthropicParameters returns the model parameters of the prompt file named as in an Anthropic messages request, with
+// the stop sequences sent as "stop_sequences". The seed, penalties, and reasoning effort are not supported by
+// Anthropic and are omitted unless they are set in Extra.
+func (pf *PromptFile) AnthropicParameters() map[string]interface{} {
+	unsupported := []string{"seed", "presence_penalty", "frequency_penalty", "reasoning_effort"}
+	return pf.providerParameters(unsupported, map[string]string{"stop": "stop_sequences"})
+}
+
+// OpenAIContentPart represents a single part of a message's content in the OpenAI chat completions request format.
+type OpenAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+	File     *OpenAIFile     `json:"file,omitempty"`
+}
+
+// OpenAIImageURL represents the image referenced by an OpenAIContentPart.
+type OpenAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// OpenAIFile represents the file referenced by an OpenAIContentPart.
+type OpenAIFile struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
+}
+
+// AnthropicContentBlock represents a single block of a message's content in the Anthropic messages request format.
+type AnthropicContentBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *AnthropicMediaSource `json:"source,omitempty"`
+	Title  string                `json:"title,omitempty"`
+}
+
+// AnthropicMediaSource represents the media referenced by an AnthropicContentBlock.
+type AnthropicMediaSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// OpenAIContent converts the content parts into the OpenAI request format.
+func OpenAIContent(parts []ContentPart) []OpenAIContentPart {
+	content := make([]OpenAIContentPart, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case TextContent:
+			content = append(content, OpenAIContentPart{Type: "text", Text: part.Text})
+		case ImageContent:
+			content = append(content, OpenAIContentPart{Type: "image_url", ImageURL: &OpenAIImageURL{URL: part.DataURI()}})
+		case FileContent:
+			content = append(content, OpenAIContentPart{Type: "file", File: &OpenAIFile{Filename: part.Name, FileData: part.DataURI()}})
+		}
+	}
+	return content
+}
+
+// AnthropicContent converts the content parts into the Anthropic request format. Plain text files are sent as text
+// documents, and all other files as base64 encoded documents.
+func AnthropicContent(parts []ContentPart) []AnthropicContentBlock {
+	content := make([]AnthropicContentBlock, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case TextContent:
+			content = append(content, AnthropicContentBlock{Type: "text", Text: part.Text})
+		case ImageContent:
+			content = append(content, AnthropicContentBlock{
+				Type:   "image",
+				Source: &AnthropicMediaSource{Type: "base64", MediaType: part.MimeType, Data: base64.StdEncoding.EncodeToString(part.Data)},
+			})
+		case FileContent:
+			source := &AnthropicMediaSource{Type: "base64", MediaType: part.MimeType, Data: base64.StdEncoding.EncodeToString(part.Data)}
+			if part.MimeType == "text/plain" {
+				source = &AnthropicMediaSource{Type: "text", MediaType: part.MimeType, Data: string(part.Data)}
+			}
+			content = append(content, AnthropicContentBlock{Type: "document", Source: source, Title: part.Name})
+		}
+	}
+	return content
+}
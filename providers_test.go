@@ -0,0 +1,101 @@
+package dotprompt
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPromptFile_OpenAITools(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-tools.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := json.Marshal(promptFile.OpenAITools())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{"type":"function","function":{"name":"get_weather","description":"Gets the current weather for a location","parameters":{"additionalProperties":false,"properties":{"days":{"type":"integer"},"location":{"description":"The city to get the weather for","type":"string"},"unit":{"enum":["celsius","fahrenheit"],"type":"string"}},"required":["location"],"type":"object"}}},{"type":"function","function":{"name":"get_time","description":"Gets the current time"}}]`
+	if string(content) != expected {
+		t.Errorf("Expected OpenAI tools to be '%s', got '%s'", expected, content)
+	}
+}
+
+func TestPromptFile_AnthropicTools(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-tools.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tools := promptFile.AnthropicTools()
+	if len(tools) != 2 {
+		t.Fatalf("Expected 2 tools, got %d", len(tools))
+	}
+
+	content, err := json.Marshal(tools[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"name":"get_time","description":"Gets the current time","input_schema":{"properties":{},"type":"object"}}`
+	if string(content) != expected {
+		t.Errorf("Expected Anthropic tool to be '%s', got '%s'", expected, content)
+	}
+
+	if tools[0].InputSchema["type"] != "object" {
+		t.Errorf("Expected input schema type to be 'object', got '%v'", tools[0].InputSchema["type"])
+	}
+}
+
+func TestPromptFile_ProviderParameters(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/model-parameters.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		actual   map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			"openai",
+			promptFile.OpenAIParameters(),
+			map[string]interface{}{
+				"temperature":       float32(0.5),
+				"max_tokens":        200,
+				"top_p":             float32(0.9),
+				"stop":              []string{"###", "END"},
+				"seed":              42,
+				"presence_penalty":  float32(0.5),
+				"frequency_penalty": float32(-0.5),
+				"reasoning_effort":  "high",
+				"logprobs":          true,
+				"service_tier":      "flex",
+			},
+		},
+		{
+			"anthropic",
+			promptFile.AnthropicParameters(),
+			map[string]interface{}{
+				"temperature":    float32(0.5),
+				"max_tokens":     200,
+				"top_p":          float32(0.9),
+				"top_k":          40,
+				"stop_sequences": []string{"###", "END"},
+				"logprobs":       true,
+				"service_tier":   "flex",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.actual, test.expected) {
+				t.Errorf("Expected parameters %v, got %v", test.expected, test.actual)
+			}
+		})
+	}
+}
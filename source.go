@@ -0,0 +1,50 @@
+package dotprompt
+
+import (
+	"errors"
+	"time"
+)
+
+// The loader types recorded in SourceMetadata.
+const (
+	FileSource    string = "file"
+	FSSource      string = "fs"
+	ArchiveSource string = "archive"
+	GitSource     string = "git"
+	HTTPSource    string = "http"
+	SQLSource     string = "sql"
+)
+
+// SourceMetadata describes where a PromptFile was loaded from. Path is the location of the prompt file within its
+// loader, such as a file path, URL, or database row name, and SHA256 is the hex encoded checksum of the raw content
+// of the prompt file. ModTime is zero when the loader cannot determine when the prompt file was last modified.
+type SourceMetadata struct {
+	Path    string
+	Loader  string
+	ModTime time.Time
+	SHA256  string
+	Size    int64
+}
+
+// newPromptFileFromSource creates a new PromptFile from the provided name and prompt data in the same way as
+// NewPromptFileWithFormat, recording the source of the data. The format is determined by the extension of the source
+// path, the checksum and size of the source are calculated from the data, and any PromptError returned identifies the
+// source.
+func newPromptFileFromSource(name string, data []byte, source SourceMetadata, options ...PromptFileOption) (*PromptFile, error) {
+	source.SHA256 = checksum(data)
+	source.Size = int64(len(data))
+
+	format, _ := promptFileFormatFromPath(source.Path)
+	promptFile, err := NewPromptFileWithFormat(name, data, format, options...)
+	if err != nil {
+		var promptError *PromptError
+		if errors.As(err, &promptError) {
+			promptError.Source = &source
+		}
+		return nil, err
+	}
+
+	promptFile.Source = source
+
+	return promptFile, nil
+}
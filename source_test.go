@@ -0,0 +1,192 @@
+package dotprompt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNewPromptFileFromFile_RecordsSource(t *testing.T) {
+	sourcePath := "test-data/basic.prompt"
+
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFile, err := NewPromptFileFromFile(sourcePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := SourceMetadata{
+		Path:    sourcePath,
+		Loader:  FileSource,
+		ModTime: info.ModTime(),
+		SHA256:  checksum(content),
+		Size:    int64(len(content)),
+	}
+
+	if promptFile.Source != expected {
+		t.Errorf("Expected source %+v, got %+v", expected, promptFile.Source)
+	}
+}
+
+func TestNewPromptFileFromFile_WithInvalidFile_ErrorIncludesSource(t *testing.T) {
+	_, err := NewPromptFileFromFile("test-data/missing-user-prompt.prompt")
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	if promptError.Source == nil || promptError.Source.Path != "test-data/missing-user-prompt.prompt" {
+		t.Fatalf("Expected error source to be test-data/missing-user-prompt.prompt, got %+v", promptError.Source)
+	}
+
+	if !strings.HasSuffix(promptError.Error(), "(source: test-data/missing-user-prompt.prompt)") {
+		t.Errorf("Expected error to include the source, got '%s'", promptError.Error())
+	}
+}
+
+func TestLoaders_RecordSource(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	content := []byte("name: sourced\n" + minimalPrompt)
+
+	fsStore := NewFSStore(fstest.MapFS{
+		"team/sourced.prompt": {Data: content, ModTime: modTime},
+	})
+
+	var bundle bytes.Buffer
+	bundleManager, err := NewManagerFromLoader(NewFSStore(fstest.MapFS{"sourced.prompt": {Data: content}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = WriteBundle(&bundle, bundleManager, ZipArchive); err != nil {
+		t.Fatal(err)
+	}
+
+	archiveStore, err := NewArchiveStoreFromBytes(bundle.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqlStore := newTestSQLStore(t)
+	sqlStore.now = func() time.Time { return modTime }
+	sqlPromptFile, err := NewPromptFile("sourced", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sqlStore.Save(sqlPromptFile, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		loader          Loader
+		expectedPath    string
+		expectedLoader  string
+		expectedModTime time.Time
+	}{
+		{"fs", fsStore, "team/sourced.prompt", FSSource, modTime},
+		{"archive", archiveStore, "sourced.prompt", ArchiveSource, time.Time{}},
+		{"sql", sqlStore, "sourced", SQLSource, modTime},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager, err := NewManagerFromLoader(test.loader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			source, ok := manager.ListSources()["sourced"]
+			if !ok {
+				t.Fatal("Expected a source for the prompt file 'sourced'")
+			}
+
+			if source.Path != test.expectedPath {
+				t.Errorf("Expected path '%s', got '%s'", test.expectedPath, source.Path)
+			}
+
+			if source.Loader != test.expectedLoader {
+				t.Errorf("Expected loader '%s', got '%s'", test.expectedLoader, source.Loader)
+			}
+
+			if !test.expectedModTime.IsZero() && !source.ModTime.Equal(test.expectedModTime) {
+				t.Errorf("Expected modification time %v, got %v", test.expectedModTime, source.ModTime)
+			}
+
+			if source.Size == 0 || len(source.SHA256) != 64 {
+				t.Errorf("Expected size and checksum to be recorded, got %+v", source)
+			}
+		})
+	}
+}
+
+func TestGitStore_Load_RecordsSource(t *testing.T) {
+	repo := newTestGitRepository(t)
+	hash := repo.commit("Add prompts", map[string]string{"prompts/team/basic.prompt": "file-store-tests/basic.prompt"})
+
+	commit, err := repo.repository.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewGitStore(repo.path, "HEAD", WithGitDirectory("prompts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := promptFiles[0].Source
+	if source.Path != "prompts/team/basic.prompt" {
+		t.Errorf("Expected path 'prompts/team/basic.prompt', got '%s'", source.Path)
+	}
+
+	if source.Loader != GitSource {
+		t.Errorf("Expected loader '%s', got '%s'", GitSource, source.Loader)
+	}
+
+	if !source.ModTime.Equal(commit.Committer.When) {
+		t.Errorf("Expected modification time %v, got %v", commit.Committer.When, source.ModTime)
+	}
+}
+
+func TestFileStore_Load_WithInvalidFile_ErrorIncludesSource(t *testing.T) {
+	root := t.TempDir()
+	invalidPath := filepath.Join(root, "invalid.prompt")
+	if err := os.WriteFile(invalidPath, []byte("prompts:\n  system: No user prompt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileStoreFromPath(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Load()
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	if promptError.Source == nil || promptError.Source.Path != invalidPath {
+		t.Errorf("Expected error source to be %s, got %+v", invalidPath, promptError.Source)
+	}
+}
@@ -0,0 +1,336 @@
+package dotprompt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const defaultSQLTableName string = "prompt_files"
+
+var validTableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLStoreError represents an error encountered in SQL store operations.
+// It contains a message describing the error and an optional underlying error.
+type SQLStoreError struct {
+	Message string
+	Err     error
+}
+
+// Error returns the error message contained in the SQLStoreError.
+func (e SQLStoreError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e SQLStoreError) Unwrap() error {
+	return e.Err
+}
+
+// SQLPromptRecord represents the audit metadata held for a prompt file in a SQLStore.
+type SQLPromptRecord struct {
+	Name      string
+	Version   int
+	Author    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SQLStoreOption configures optional behaviour of a SQLStore.
+type SQLStoreOption func(s *SQLStore)
+
+// WithTableName sets the name of the table used to store prompt files, which defaults to "prompt_files". The name
+// may be qualified with a schema, such as "prompts.prompt_files".
+func WithTableName(name string) SQLStoreOption {
+	return func(s *SQLStore) {
+		s.tableName = name
+	}
+}
+
+// WithDollarPlaceholders configures the store to use numbered placeholders ($1, $2, ...) in queries, as required
+// by PostgreSQL drivers. By default, question mark placeholders are used.
+func WithDollarPlaceholders() SQLStoreOption {
+	return func(s *SQLStore) {
+		s.placeholder = func(position int) string {
+			return fmt.Sprintf("$%d", position)
+		}
+	}
+}
+
+// SQLStore represents a storage system which holds prompt files in a SQL database, along with audit metadata. Each
+// prompt file is stored as a single row in a table with the following schema, which can be created using
+// CreateSchema:
+//
+//	CREATE TABLE prompt_files (
+//	    name       VARCHAR(255) NOT NULL PRIMARY KEY,
+//	    version    INTEGER      NOT NULL,
+//	    body       TEXT         NOT NULL,
+//	    author     VARCHAR(255) NOT NULL,
+//	    created_at TIMESTAMP    NOT NULL,
+//	    updated_at TIMESTAMP    NOT NULL
+//	)
+//
+// The body holds the YAML produced by Serialize, and the version is incremented each time the prompt file is saved.
+type SQLStore struct {
+	db          *sql.DB
+	tableName   string
+	placeholder func(position int) string
+	now         func() time.Time
+}
+
+// NewSQLStore creates a new SQLStore which reads and writes prompt files using the provided database.
+func NewSQLStore(db *sql.DB, options ...SQLStoreOption) (*SQLStore, error) {
+	if db == nil {
+		return nil, &SQLStoreError{
+			Message: "The specified database is nil",
+		}
+	}
+
+	store := &SQLStore{
+		db:        db,
+		tableName: defaultSQLTableName,
+		placeholder: func(_ int) string {
+			return "?"
+		},
+		now: time.Now,
+	}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	if !validTableNameRegex.MatchString(store.tableName) {
+		return nil, &SQLStoreError{
+			Message: fmt.Sprintf("The specified table name is invalid: %s", store.tableName),
+		}
+	}
+
+	return store, nil
+}
+
+// CreateSchema creates the table used to store prompt files if it does not already exist.
+func (s *SQLStore) CreateSchema() error {
+	_, err := s.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    name       VARCHAR(255) NOT NULL PRIMARY KEY,
+    version    INTEGER      NOT NULL,
+    body       TEXT         NOT NULL,
+    author     VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP    NOT NULL,
+    updated_at TIMESTAMP    NOT NULL
+)`, s.tableName))
+	if err != nil {
+		return &SQLStoreError{
+			Message: fmt.Sprintf("failed to create schema: %v", err),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// Load retrieves all prompt files from the database and returns a slice of PromptFile objects, ordered by name, or
+// an error.
+func (s *SQLStore) Load() ([]PromptFile, error) {
+	return s.LoadContext(context.Background())
+}
+
+// LoadContext retrieves all prompt files from the database in the same way as Load, cancelling the query if the
+// context is done.
+func (s *SQLStore) LoadContext(ctx context.Context) ([]PromptFile, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT name, body, updated_at FROM %s ORDER BY name", s.tableName))
+	if err != nil {
+		return nil, &SQLStoreError{
+			Message: fmt.Sprintf("failed to query prompt files: %v", err),
+			Err:     err,
+		}
+	}
+	defer rows.Close()
+
+	promptFiles := make([]PromptFile, 0)
+	for rows.Next() {
+		var name, body string
+		var updatedAt time.Time
+		if err = rows.Scan(&name, &body, &updatedAt); err != nil {
+			return nil, &SQLStoreError{
+				Message: fmt.Sprintf("failed to read prompt file: %v", err),
+				Err:     err,
+			}
+		}
+
+		// Namespaced prompt files are stored under their qualified name, with the namespace removed from the body
+		namespace, baseName := "", name
+		if index := strings.LastIndex(name, "/"); index >= 0 {
+			namespace, baseName = name[:index], name[index+1:]
+		}
+
+		source := SourceMetadata{Path: name, Loader: SQLSource, ModTime: updatedAt}
+		promptFile, promptFileErr := newPromptFileFromSource(baseName, []byte(body), source)
+		if promptFileErr != nil {
+			return nil, promptFileErr
+		}
+
+		if namespace != "" {
+			promptFile.Namespace = namespace
+			promptFile.Name = namespace + "/" + promptFile.Name
+		}
+		promptFiles = append(promptFiles, *promptFile)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, &SQLStoreError{
+			Message: fmt.Sprintf("failed to read prompt files: %v", err),
+			Err:     err,
+		}
+	}
+
+	return promptFiles, nil
+}
+
+// Save writes the prompt file to the database, recording the author of the change. A new prompt file is created with
+// version 1, and saving an existing prompt file replaces its body and increments its version, so that concurrent saves
+// each create a new version. The prompt file is serialized and parsed again before saving, so invalid prompt files are
+// never written.
+func (s *SQLStore) Save(promptFile *PromptFile, author string) error {
+	if promptFile == nil {
+		return &SQLStoreError{
+			Message: "The specified prompt file is nil",
+		}
+	}
+
+	// Prompt files are stored in the native or front matter format, which are detected automatically when loaded
+	stored := *promptFile
+	if stored.Format != FrontMatterFormat {
+		stored.Format = NativeFormat
+	}
+
+	body, err := stored.Serialize()
+	if err != nil {
+		return err
+	}
+
+	parsed, err := NewPromptFile(strings.TrimPrefix(promptFile.Name, promptFile.Namespace+"/"), body)
+	if err != nil {
+		return err
+	}
+
+	name := parsed.Name
+	if promptFile.Namespace != "" {
+		name = promptFile.Namespace + "/" + name
+	}
+	now := s.now().UTC()
+
+	// The version is incremented by the database rather than read and written back, so that concurrent saves each
+	// create a new version. If there is no row to update then one is inserted, and if a concurrent save inserted the
+	// row first then the update is tried again.
+	updated, err := s.update(name, string(body), author, now)
+	if err == nil && !updated {
+		_, err = s.db.Exec(
+			fmt.Sprintf(
+				"INSERT INTO %s (name, version, body, author, created_at, updated_at) VALUES (%s, %s, %s, %s, %s, %s)",
+				s.tableName, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4), s.placeholder(5), s.placeholder(6),
+			),
+			name, 1, string(body), author, now, now,
+		)
+		if err != nil {
+			if updated, _ = s.update(name, string(body), author, now); updated {
+				err = nil
+			}
+		}
+	}
+
+	if err != nil {
+		return &SQLStoreError{
+			Message: fmt.Sprintf("failed to save prompt file %s: %v", name, err),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// update replaces the body of the prompt file with the specified name and increments its version, returning false if
+// no prompt file with that name exists.
+func (s *SQLStore) update(name string, body string, author string, now time.Time) (bool, error) {
+	result, err := s.db.Exec(
+		fmt.Sprintf(
+			"UPDATE %s SET version = version + 1, body = %s, author = %s, updated_at = %s WHERE name = %s",
+			s.tableName, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4),
+		),
+		body, author, now, name,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Delete removes the prompt file with the specified name from the database. Returns an error if no prompt file with
+// that name exists.
+func (s *SQLStore) Delete(name string) error {
+	result, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE name = %s", s.tableName, s.placeholder(1)), name)
+	if err != nil {
+		return &SQLStoreError{
+			Message: fmt.Sprintf("failed to delete prompt file %s: %v", name, err),
+			Err:     err,
+		}
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return &SQLStoreError{
+			Message: fmt.Sprintf("failed to delete prompt file %s: %v", name, err),
+			Err:     err,
+		}
+	}
+
+	if affected == 0 {
+		return &SQLStoreError{
+			Message: fmt.Sprintf("prompt file not found: %s", name),
+		}
+	}
+
+	return nil
+}
+
+// List returns the audit metadata of every prompt file in the database, ordered by name.
+func (s *SQLStore) List() ([]SQLPromptRecord, error) {
+	rows, err := s.db.Query(fmt.Sprintf(
+		"SELECT name, version, author, created_at, updated_at FROM %s ORDER BY name", s.tableName,
+	))
+	if err != nil {
+		return nil, &SQLStoreError{
+			Message: fmt.Sprintf("failed to query prompt files: %v", err),
+			Err:     err,
+		}
+	}
+	defer rows.Close()
+
+	records := make([]SQLPromptRecord, 0)
+	for rows.Next() {
+		var record SQLPromptRecord
+		if err = rows.Scan(&record.Name, &record.Version, &record.Author, &record.CreatedAt, &record.UpdatedAt); err != nil {
+			return nil, &SQLStoreError{
+				Message: fmt.Sprintf("failed to read prompt file metadata: %v", err),
+				Err:     err,
+			}
+		}
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, &SQLStoreError{
+			Message: fmt.Sprintf("failed to read prompt file metadata: %v", err),
+			Err:     err,
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,343 @@
+package dotprompt
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func newTestSQLStore(t *testing.T, options ...SQLStoreOption) *SQLStore {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "prompts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	store, err := NewSQLStore(db, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.CreateSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestSQLStore_SaveAndLoad(t *testing.T) {
+	store := newTestSQLStore(t)
+
+	promptFile, err := NewPromptFileFromFile("file-store-tests/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Save(promptFile, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 1 {
+		t.Fatalf("Expected 1 prompt file, got %d", len(promptFiles))
+	}
+
+	loaded := promptFiles[0]
+	if loaded.Name != promptFile.Name {
+		t.Errorf("Expected name '%s', got '%s'", promptFile.Name, loaded.Name)
+	}
+
+	if loaded.Prompts.User != promptFile.Prompts.User {
+		t.Errorf("Expected user prompt '%s', got '%s'", promptFile.Prompts.User, loaded.Prompts.User)
+	}
+}
+
+func TestSQLStore_SaveAndLoad_WithNamespace(t *testing.T) {
+	store := newTestSQLStore(t)
+
+	fsys := fstest.MapFS{
+		"support/summarise.prompt": {Data: []byte(minimalPrompt)},
+	}
+
+	promptFiles, err := NewFSStore(fsys, WithNamespaces()).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Save(&promptFiles[0], "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 1 {
+		t.Fatalf("Expected 1 prompt file, got %d", len(promptFiles))
+	}
+
+	loaded := promptFiles[0]
+	if loaded.Name != "support/summarise" || loaded.Namespace != "support" {
+		t.Errorf("Expected name 'support/summarise' in namespace 'support', got '%s' in '%s'", loaded.Name, loaded.Namespace)
+	}
+
+	// Saving the reloaded prompt file updates the same row rather than adding one without the namespace
+	if err = store.Save(&loaded, "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0].Name != "support/summarise" || records[0].Version != 2 {
+		t.Errorf("Expected a single record 'support/summarise' at version 2, got %+v", records)
+	}
+}
+
+func TestSQLStore_Save_IncrementsVersion(t *testing.T) {
+	store := newTestSQLStore(t)
+
+	created := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	store.now = func() time.Time { return created }
+
+	promptFile, err := NewPromptFileFromFile("file-store-tests/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Save(promptFile, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	store.now = func() time.Time { return updated }
+	promptFile.Prompts.System = "You are a careful assistant"
+	if err = store.Save(promptFile, "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+
+	record := records[0]
+	if record.Version != 2 {
+		t.Errorf("Expected version 2, got %d", record.Version)
+	}
+
+	if record.Author != "bob" {
+		t.Errorf("Expected author 'bob', got '%s'", record.Author)
+	}
+
+	if !record.CreatedAt.Equal(created) {
+		t.Errorf("Expected created at %v, got %v", created, record.CreatedAt)
+	}
+
+	if !record.UpdatedAt.Equal(updated) {
+		t.Errorf("Expected updated at %v, got %v", updated, record.UpdatedAt)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if promptFiles[0].Prompts.System != "You are a careful assistant" {
+		t.Errorf("Expected updated system prompt, got '%s'", promptFiles[0].Prompts.System)
+	}
+}
+
+func TestSQLStore_Save_Concurrently(t *testing.T) {
+	// The busy timeout makes concurrent writers wait for the database lock rather than failing immediately
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "prompts.db")+"?_pragma=busy_timeout(10000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	store, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.CreateSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	promptFile, err := NewPromptFileFromFile("file-store-tests/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const saves = 64
+	errs := make(chan error, saves)
+
+	var wg sync.WaitGroup
+	for i := 0; i < saves; i++ {
+		wg.Add(1)
+		go func(author string) {
+			defer wg.Done()
+			errs <- store.Save(promptFile, author)
+		}(fmt.Sprintf("author-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err = range errs {
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	}
+
+	records, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0].Version != saves {
+		t.Errorf("Expected a single record at version %d, got %+v", saves, records)
+	}
+}
+
+func TestSQLStore_Save_WithInvalidPromptFile_ReturnsError(t *testing.T) {
+	store := newTestSQLStore(t)
+
+	promptFile := &PromptFile{Name: "invalid", Model: "gpt-4o"}
+
+	err := store.Save(promptFile, "alice")
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	records, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 0 {
+		t.Errorf("Expected no records, got %d", len(records))
+	}
+}
+
+func TestSQLStore_Delete(t *testing.T) {
+	store := newTestSQLStore(t, WithTableName("custom_prompts"))
+
+	promptFile, err := NewPromptFileFromFile("file-store-tests/basic.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Save(promptFile, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.Delete(promptFile.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFiles) != 0 {
+		t.Errorf("Expected no prompt files, got %d", len(promptFiles))
+	}
+
+	err = store.Delete(promptFile.Name)
+
+	var storeError *SQLStoreError
+	if !errors.As(err, &storeError) {
+		t.Fatalf("Expected SQLStoreError, got %T", err)
+	}
+
+	expectedError := "prompt file not found: basic"
+	if storeError.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, storeError.Error())
+	}
+}
+
+func TestSQLStore_WithManager(t *testing.T) {
+	store := newTestSQLStore(t)
+
+	for _, path := range []string{"file-store-tests/basic.prompt", "file-store-tests/sub-dir/another-with-name.prompt"} {
+		promptFile, err := NewPromptFileFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = store.Save(promptFile, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewManagerFromLoader(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = manager.GetPromptFile("another-example-with-name"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewSQLStore_WithInvalidArguments(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name          string
+		db            *sql.DB
+		options       []SQLStoreOption
+		expectedError string
+	}{
+		{"nil-database", nil, nil, "The specified database is nil"},
+		{"invalid-table-name", db, []SQLStoreOption{WithTableName("prompts; DROP TABLE users")}, "The specified table name is invalid: prompts; DROP TABLE users"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewSQLStore(test.db, test.options...)
+
+			var storeError *SQLStoreError
+			if !errors.As(err, &storeError) {
+				t.Fatalf("Expected SQLStoreError, got %T", err)
+			}
+
+			if storeError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, storeError.Error())
+			}
+		})
+	}
+}
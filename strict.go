@@ -0,0 +1,237 @@
+package dotprompt
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed schema/prompt-file.schema.json
+var promptFileSchema []byte
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// inlineFieldKey is the key used by yamlFieldTypes for the value type of an inline map, which holds any keys which do
+// not match another field.
+const inlineFieldKey = ",inline"
+
+// JSONSchema returns the JSON Schema describing the prompt file format, which can be used by editors to provide
+// validation and autocompletion. The schema is also available in the schema directory of the repository.
+func JSONSchema() []byte {
+	return slices.Clone(promptFileSchema)
+}
+
+// unknownField represents a field in a prompt file which is not part of the prompt file format.
+type unknownField struct {
+	path string
+	line int
+}
+
+// String returns the path of the field, followed by its line number if known.
+func (f unknownField) String() string {
+	if f.line > 0 {
+		return fmt.Sprintf("%s on line %d", f.path, f.line)
+	}
+	return f.path
+}
+
+// checkUnknownFields returns a PromptError listing any fields in the prompt data which are not part of the format.
+func checkUnknownFields(data []byte, format FileFormat) error {
+	var fields []unknownField
+	var err error
+
+	switch format {
+	case TOMLFormat:
+		fields, err = unknownTOMLFields(data)
+	case JSONFormat:
+		// JSON is parsed as YAML to find the line numbers of unknown fields, falling back to the JSON decoder for the
+		// few documents which are valid JSON but not valid YAML
+		if fields, err = unknownYAMLFields(data, reflect.TypeOf(PromptFile{}), false); err != nil {
+			fields, err = unknownJSONFields(data)
+		}
+	default:
+		if front, _, ok := splitFrontMatter(data); ok {
+			fields, err = unknownYAMLFields(front, reflect.TypeOf(frontMatter{}), true)
+		} else {
+			fields, err = unknownYAMLFields(data, reflect.TypeOf(PromptFile{}), false)
+		}
+	}
+
+	if err != nil {
+		return &PromptError{
+			Message: fmt.Sprintf("failed to parse prompt file: %v", err),
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, 0, len(fields))
+	for _, field := range fields {
+		descriptions = append(descriptions, field.String())
+	}
+
+	message := "unknown field %s"
+	if len(fields) > 1 {
+		message = "unknown fields %s"
+	}
+
+	return &PromptError{
+		Message: fmt.Sprintf(message, strings.Join(descriptions, ", ")),
+	}
+}
+
+// unknownYAMLFields returns the fields of the YAML document which do not match a field of the type. When extensions
+// are allowed, top level keys containing a dot are treated as extension fields rather than unknown fields.
+func unknownYAMLFields(data []byte, t reflect.Type, allowExtensions bool) ([]unknownField, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+
+	fields := make([]unknownField, 0)
+	collectUnknownFields(&node, t, "", allowExtensions, &fields)
+
+	return fields, nil
+}
+
+// collectUnknownFields walks the YAML node alongside the type it is decoded into, adding any mapping keys which do
+// not match a field of a struct to fields.
+func collectUnknownFields(node *yaml.Node, t reflect.Type, path string, allowExtensions bool, fields *[]unknownField) {
+	for node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		fieldTypes := yamlFieldTypes(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldPath := joinFieldPath(path, key.Value)
+
+			fieldType, ok := fieldTypes[key.Value]
+			if !ok {
+				fieldType, ok = fieldTypes[inlineFieldKey]
+			}
+			if !ok {
+				if !allowExtensions || !strings.Contains(key.Value, ".") {
+					*fields = append(*fields, unknownField{path: fieldPath, line: key.Line})
+				}
+				continue
+			}
+
+			collectUnknownFields(value, fieldType, fieldPath, false, fields)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		for i, item := range node.Content {
+			collectUnknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), false, fields)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			collectUnknownFields(node.Content[i+1], t.Elem(), joinFieldPath(path, node.Content[i].Value), false, fields)
+		}
+	}
+}
+
+// yamlFieldTypes returns the types of the exported fields of the struct, keyed by their YAML names. The value type of an
+// inline map is keyed by inlineFieldKey.
+func yamlFieldTypes(t reflect.Type) map[string]reflect.Type {
+	fieldTypes := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, flags, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if flags == "inline" && field.Type.Kind() == reflect.Map {
+			fieldTypes[inlineFieldKey] = field.Type.Elem()
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fieldTypes[name] = field.Type
+	}
+	return fieldTypes
+}
+
+// joinFieldPath appends the key to the path of its parent.
+func joinFieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// unknownJSONFields returns the first field of the JSON document which is not part of the prompt file format.
+func unknownJSONFields(data []byte) ([]unknownField, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&PromptFile{}); err != nil {
+		field, found := strings.CutPrefix(err.Error(), "json: unknown field ")
+		if !found {
+			return nil, err
+		}
+		return []unknownField{{path: strings.Trim(field, `"`)}}, nil
+	}
+
+	return nil, nil
+}
+
+// unknownTOMLFields returns the keys of the TOML document which are not part of the prompt file format. Tables which
+// are not part of the format are reported without the keys they contain.
+func unknownTOMLFields(data []byte) ([]unknownField, error) {
+	metadata, err := toml.Decode(string(data), &PromptFile{})
+	if err != nil {
+		return nil, err
+	}
+
+	undecoded := metadata.Undecoded()
+	fields := make([]unknownField, 0, len(undecoded))
+	for _, key := range undecoded {
+		if len(key) > 1 && slices.ContainsFunc(undecoded, func(parent toml.Key) bool {
+			return len(parent) < len(key) && slices.Equal(parent, key[:len(parent)])
+		}) {
+			continue
+		}
+		fields = append(fields, unknownField{path: key.String()})
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,189 @@
+package dotprompt
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewPromptFileFromFile_WithStrict_ReportsUnknownField(t *testing.T) {
+	_, err := NewPromptFileFromFile("test-data/basic-fsp.prompt", WithStrict())
+
+	var promptError *PromptError
+	if !errors.As(err, &promptError) {
+		t.Fatalf("Expected PromptError, got %T", err)
+	}
+
+	expected := "unknown field config.name on line 2"
+	if promptError.Message != expected {
+		t.Errorf("Expected error '%s', got '%s'", expected, promptError.Message)
+	}
+}
+
+func TestNewPromptFile_WithStrict_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		format        FileFormat
+		expectedError string
+	}{
+		{
+			"nested-fields",
+			"config:\n  input:\n    parameter:\n      topic: string\nprompts:\n  user: Hello\n  sytem: Be brief\n",
+			NativeFormat,
+			"unknown fields config.input.parameter on line 3, prompts.sytem on line 7",
+		},
+		{
+			"message-field",
+			"messages:\n  - role: system\n    content: Be brief\n  - role: user\n    content: Hello\n    nmae: alice\n",
+			NativeFormat,
+			"unknown field messages[1].nmae on line 6",
+		},
+		{
+			"override-field",
+			minimalPrompt + "overrides:\n  prod:\n    config:\n      temprature: 0.2\n",
+			NativeFormat,
+			"unknown field overrides.prod.config.temprature on line 6",
+		},
+		{
+			"front-matter",
+			"\n---\nmodel: test\ninput:\n  defaults:\n    topic: Go\n---\nHello\n",
+			NativeFormat,
+			"unknown field input.defaults on line 5",
+		},
+		{
+			"json",
+			"{\n  \"prompts\": {\n    \"user\": \"Hello\"\n  },\n  \"fewshots\": []\n}\n",
+			JSONFormat,
+			"unknown field fewshots on line 5",
+		},
+		{
+			"toml",
+			"model = \"test\"\n\n[prompts]\nuser = \"Hello\"\n\n[config.other]\nvalue = 1\n",
+			TOMLFormat,
+			"unknown field config.other",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewPromptFileWithFormat(test.name, []byte(test.data), test.format); err != nil {
+				t.Fatalf("Expected prompt file to load without strict, got %v", err)
+			}
+
+			_, err := NewPromptFileWithFormat(test.name, []byte(test.data), test.format, WithStrict())
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestNewPromptFile_WithStrict_AllowsFreeFormFields(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			"defaults-and-tool-parameters",
+			"config:\n  input:\n    parameters:\n      topic: string\n    default:\n      topic: Go\nprompts:\n  user: Hello\ntools:\n  - name: lookup\n    parameters:\n      type: object\n      properties:\n        query:\n          type: string\n",
+		},
+		{
+			"front-matter-extensions",
+			"---\nmodel: test\nmyteam.owner: alice\ninput:\n  schema:\n    topic: string, the topic\n---\nHello {{ topic }}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewPromptFile(test.name, []byte(test.data), WithStrict()); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFSStore_Load_WithStrictPromptFiles(t *testing.T) {
+	store := NewFSStore(fstest.MapFS{
+		"valid.prompt":   {Data: []byte(minimalPrompt)},
+		"invalid.prompt": {Data: []byte("prompts:\n  user: Hello\nmodle: gpt-4o\n")},
+	}, WithPromptFileOptions(WithStrict()), WithLenient())
+
+	promptFiles, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := promptFileNames(promptFiles); !slices.Equal(names, []string{"validprompt"}) {
+		t.Errorf("Expected prompt files [validprompt], got %v", names)
+	}
+
+	loadErrors := store.Errors()
+	if len(loadErrors) != 1 || !strings.HasPrefix(loadErrors[0].Err.Error(), "unknown field modle on line 3") {
+		t.Errorf("Expected an unknown field error for invalid.prompt, got %v", loadErrors)
+	}
+}
+
+func TestJSONSchema_MatchesPromptFileFields(t *testing.T) {
+	var schema struct {
+		Properties  map[string]json.RawMessage `json:"properties"`
+		Definitions map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		definition string
+		value      interface{}
+	}{
+		{"", PromptFile{}},
+		{"config", PromptConfig{}},
+		{"input", InputSchema{}},
+		{"prompts", Prompts{}},
+		{"message", MessageTemplate{}},
+		{"fewShot", FewShotPromptPair{}},
+		{"tool", Tool{}},
+		{"override", ProfileOverride{}},
+		{"configOverride", ConfigOverride{}},
+	}
+
+	for _, test := range tests {
+		t.Run(reflect.TypeOf(test.value).Name(), func(t *testing.T) {
+			properties := schema.Properties
+			if test.definition != "" {
+				properties = schema.Definitions[test.definition].Properties
+			}
+
+			expected := make([]string, 0)
+			for name := range yamlFieldTypes(reflect.TypeOf(test.value)) {
+				expected = append(expected, name)
+			}
+			slices.Sort(expected)
+
+			actual := make([]string, 0, len(properties))
+			for name := range properties {
+				actual = append(actual, name)
+			}
+			slices.Sort(actual)
+
+			if !slices.Equal(actual, expected) {
+				t.Errorf("Expected schema properties %v, got %v", expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package dotprompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+var validToolNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Tool represents a tool, or function, which the model may call. The parameters of the tool are described using a
+// JSON Schema object.
+type Tool struct {
+	Name        string                 `yaml:"name" json:"name" toml:"name"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty" toml:"description,omitempty"`
+	Parameters  map[string]interface{} `yaml:"parameters,omitempty" json:"parameters,omitempty" toml:"parameters,omitempty"`
+}
+
+// GetTool retrieves the tool with the specified name from the prompt file.
+// Returns the Tool and a boolean indicating if the tool was found.
+func (pf *PromptFile) GetTool(name string) (Tool, bool) {
+	for _, tool := range pf.Tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return Tool{}, false
+}
+
+// DecodeToolArguments validates the JSON encoded arguments of a tool call returned by the model against the
+// parameters schema of the named tool, and then decodes them into target. A nil target only validates the arguments.
+func (pf *PromptFile) DecodeToolArguments(name string, arguments []byte, target interface{}) error {
+	tool, ok := pf.GetTool(name)
+	if !ok {
+		return &PromptError{
+			Message: fmt.Sprintf("tool not found: %s", name),
+		}
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(arguments, &decoded); err != nil {
+		return &PromptError{
+			Message: fmt.Sprintf("failed to parse arguments for tool %s: %v", name, err),
+		}
+	}
+
+	if tool.Parameters != nil {
+		if err := validateJSONSchema(tool.Parameters, decoded, "$"); err != nil {
+			return &PromptError{
+				Message: fmt.Sprintf("invalid arguments for tool %s: %v", name, err),
+			}
+		}
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(arguments, target); err != nil {
+		return &PromptError{
+			Message: fmt.Sprintf("failed to decode arguments for tool %s: %v", name, err),
+		}
+	}
+
+	return nil
+}
+
+// validateTools checks that each tool has a valid and unique name, and that its parameters, if provided, describe
+// an object.
+func validateTools(tools []Tool) error {
+	names := make(map[string]bool, len(tools))
+
+	for _, tool := range tools {
+		if !validToolNameRegex.MatchString(tool.Name) {
+			return &PromptError{
+				Message: fmt.Sprintf("invalid tool name: %s", tool.Name),
+			}
+		}
+
+		if names[tool.Name] {
+			return &PromptError{
+				Message: fmt.Sprintf("duplicate tool name: %s", tool.Name),
+			}
+		}
+		names[tool.Name] = true
+
+		if tool.Parameters == nil {
+			continue
+		}
+
+		if schemaType, ok := tool.Parameters["type"]; !ok || schemaType != "object" {
+			return &PromptError{
+				Message: fmt.Sprintf("parameters for tool %s must be a JSON Schema of type object", tool.Name),
+			}
+		}
+	}
+
+	return nil
+}
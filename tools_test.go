@@ -0,0 +1,179 @@
+package dotprompt
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewPromptFileFromFile_WithTools(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-tools.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(promptFile.Tools) != 2 {
+		t.Fatalf("Expected 2 tools, got %d", len(promptFile.Tools))
+	}
+
+	tool, ok := promptFile.GetTool("get_weather")
+	if !ok {
+		t.Fatal("Expected tool 'get_weather' to be found")
+	}
+
+	if tool.Description != "Gets the current weather for a location" {
+		t.Errorf("Expected description to be 'Gets the current weather for a location', got '%s'", tool.Description)
+	}
+
+	if tool.Parameters["type"] != "object" {
+		t.Errorf("Expected parameters type to be 'object', got '%v'", tool.Parameters["type"])
+	}
+
+	if _, ok = promptFile.GetTool("does-not-exist"); ok {
+		t.Error("Expected tool 'does-not-exist' to not be found")
+	}
+}
+
+func TestNewPromptFile_WithInvalidTools_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{"invalid-name", "prompts:\n  user: User\ntools:\n  - name: get weather", "invalid tool name: get weather"},
+		{"empty-name", "prompts:\n  user: User\ntools:\n  - description: No name", "invalid tool name: "},
+		{"duplicate-name", "prompts:\n  user: User\ntools:\n  - name: a\n  - name: a", "duplicate tool name: a"},
+		{"non-object-parameters", "prompts:\n  user: User\ntools:\n  - name: a\n    parameters:\n      type: string", "parameters for tool a must be a JSON Schema of type object"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewPromptFile(test.name, []byte(test.content))
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if promptError.Error() != test.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestPromptFile_Serialize_WithTools(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-tools.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialized, err := promptFile.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roundTripped, err := NewPromptFile("round-trip", serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(promptFile.Tools, roundTripped.Tools) {
+		t.Errorf("Expected tools to be %+v, got %+v", promptFile.Tools, roundTripped.Tools)
+	}
+}
+
+func TestPromptFile_DecodeToolArguments(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-tools.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var arguments struct {
+		Location string `json:"location"`
+		Unit     string `json:"unit"`
+		Days     int    `json:"days"`
+	}
+
+	err = promptFile.DecodeToolArguments("get_weather", []byte(`{"location": "Valletta", "unit": "celsius", "days": 3}`), &arguments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if arguments.Location != "Valletta" || arguments.Unit != "celsius" || arguments.Days != 3 {
+		t.Errorf("Unexpected decoded arguments: %+v", arguments)
+	}
+}
+
+func TestPromptFile_DecodeToolArguments_WithoutParameters(t *testing.T) {
+	promptFile, err := NewPromptFileFromFile("test-data/with-tools.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = promptFile.DecodeToolArguments("get_time", []byte(`{}`), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPromptFile_DecodeToolArguments_WithInvalidArguments_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name          string
+		tool          string
+		arguments     string
+		expectedError string
+	}{
+		{"unknown-tool", "get_news", `{}`, "tool not found: get_news"},
+		{"invalid-json", "get_weather", `{`, "failed to parse arguments for tool get_weather"},
+		{"missing-required", "get_weather", `{"unit": "celsius"}`, "invalid arguments for tool get_weather: $: missing required property location"},
+		{"wrong-type", "get_weather", `{"location": 12}`, "invalid arguments for tool get_weather: $.location: expected string"},
+		{"not-in-enum", "get_weather", `{"location": "Valletta", "unit": "kelvin"}`, "invalid arguments for tool get_weather: $.unit: value kelvin is not one of [celsius fahrenheit]"},
+		{"not-integer", "get_weather", `{"location": "Valletta", "days": 1.5}`, "invalid arguments for tool get_weather: $.days: expected integer"},
+		{"additional-property", "get_weather", `{"location": "Valletta", "extra": true}`, "invalid arguments for tool get_weather: $.extra: additional property is not allowed"},
+		{"not-object", "get_weather", `[]`, "invalid arguments for tool get_weather: $: expected object"},
+	}
+
+	promptFile, err := NewPromptFileFromFile("test-data/with-tools.prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := promptFile.DecodeToolArguments(test.tool, []byte(test.arguments), nil)
+
+			var promptError *PromptError
+			if !errors.As(err, &promptError) {
+				t.Fatalf("Expected PromptError, got %T", err)
+			}
+
+			if !strings.HasPrefix(promptError.Error(), test.expectedError) {
+				t.Errorf("Expected error to start with '%s', got '%s'", test.expectedError, promptError.Error())
+			}
+		})
+	}
+}
+
+func TestValidateJSONSchema_WithArrays(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": []interface{}{"number", "null"}, "enum": []interface{}{1, 2, nil}},
+	}
+
+	if err := validateJSONSchema(schema, []interface{}{float64(1), nil, float64(2)}, "$"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := validateJSONSchema(schema, []interface{}{float64(1), "two"}, "$")
+	if err == nil || err.Error() != "$[1]: expected one of [number null]" {
+		t.Errorf("Expected error '$[1]: expected one of [number null]', got '%v'", err)
+	}
+
+	err = validateJSONSchema(schema, []interface{}{float64(3)}, "$")
+	if err == nil || err.Error() != "$[0]: value 3 is not one of [1 2 <nil>]" {
+		t.Errorf("Expected error '$[0]: value 3 is not one of [1 2 <nil>]', got '%v'", err)
+	}
+}